@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// module interface compatibility
+type ChangeLevel int
+
+const (
+	LevelNone ChangeLevel = iota
+	LevelPatch
+	LevelAdditive
+	LevelBreaking
+)
+
+var changeLevelNames = []string{"none", "patch", "additive", "breaking"}
+var changeLevelBumps = []string{"none", "patch", "minor", "major"}
+
+func (l ChangeLevel) String() string {
+	return changeLevelNames[l]
+}
+
+func (l ChangeLevel) Bump() string {
+	return changeLevelBumps[l]
+}
+
+func (l ChangeLevel) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.String())
+}
+
+func parseBump(bump string) (ChangeLevel, error) {
+	index := Index(changeLevelBumps, bump)
+	if index < 0 {
+		return LevelNone, fmt.Errorf("unknown version bump '%s', expected one of %v", bump, changeLevelBumps)
+	}
+	return ChangeLevel(index), nil
+}
+
+type CompatChange struct {
+	Level   ChangeLevel `form:"Level" json:"Level" xml:"Level"`
+	Kind    string      `form:"Kind" json:"Kind" xml:"Kind"`
+	Name    string      `form:"Name" json:"Name" xml:"Name"`
+	Message string      `form:"Message" json:"Message" xml:"Message"`
+}
+
+type CompatReport struct {
+	Module  string         `form:"Module" json:"Module" xml:"Module"`
+	Level   ChangeLevel    `form:"Level" json:"Level" xml:"Level"`
+	Bump    string         `form:"Bump" json:"Bump" xml:"Bump"`
+	Changes []CompatChange `form:"Changes" json:"Changes" xml:"Changes"`
+}
+
+func (r *CompatReport) add(level ChangeLevel, kind string, name string, message string) {
+	r.Changes = append(r.Changes, CompatChange{Level: level, Kind: kind, Name: name, Message: message})
+	if level > r.Level {
+		r.Level = level
+		r.Bump = level.Bump()
+	}
+}
+
+// classifies interface changes between two versions of one module
+func CheckCompatibility(name string, before SnapshotModule, after SnapshotModule) *CompatReport {
+	report := &CompatReport{Module: name, Level: LevelNone, Bump: LevelNone.Bump()}
+	diff := diffModules(name, before, after)
+
+	for _, input := range diff.InputsRemoved {
+		report.add(LevelBreaking, "input", input, "input removed, callers passing it will fail")
+	}
+	for _, input := range diff.InputsAdded {
+		if after.Inputs[input].Required {
+			report.add(LevelBreaking, "input", input, "required input added")
+		} else {
+			report.add(LevelAdditive, "input", input, "optional input added")
+		}
+	}
+	for _, required := range diff.RequiredChanged {
+		if required.After {
+			report.add(LevelBreaking, "input", required.Input, "input became required")
+		} else {
+			report.add(LevelAdditive, "input", required.Input, "input became optional")
+		}
+	}
+	for _, output := range diff.OutputsRemoved {
+		report.add(LevelBreaking, "output", output, "output removed")
+	}
+	for _, output := range diff.OutputsAdded {
+		report.add(LevelAdditive, "output", output, "output added")
+	}
+	// wiring of added and removed inputs and outputs is reported by the change itself
+	for _, edges := range diff.Edges {
+		_, beforeInput := before.Inputs[edges.Name]
+		_, afterInput := after.Inputs[edges.Name]
+		if "input" == edges.Kind && !(beforeInput && afterInput) {
+			continue
+		}
+		_, beforeOutput := before.Outputs[edges.Name]
+		_, afterOutput := after.Outputs[edges.Name]
+		if "output" == edges.Kind && !(beforeOutput && afterOutput) {
+			continue
+		}
+		report.add(LevelPatch, edges.Kind, edges.Name, fmt.Sprintf("wiring changed: +%v -%v", edges.Added, edges.Removed))
+	}
+	return report
+}
+
+func (r *CompatReport) WriteText(buffer *bytes.Buffer) {
+	for _, change := range r.Changes {
+		fmt.Fprintf(buffer, "%-8s %s %s: %s\n", change.Level, change.Kind, change.Name, change.Message)
+	}
+	fmt.Fprintf(buffer, "module %s: %s changes, suggested version bump: %s\n", r.Module, r.Level, r.Bump)
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// command
+func runCompat(args []string) error {
+	flags := flag.NewFlagSet("compat", flag.ExitOnError)
+	moduleName := flags.String("module", ".", "module name to compare when arguments contain more than one module")
+	declaredBump := flags.String("bump", "", "version bump planned for the release (major, minor, patch), breaking changes fail unless major")
+	jsonFormat := flags.Bool("json", false, "print report as json")
+	flags.Parse(args)
+
+	if 2 != flags.NArg() {
+		return fmt.Errorf("expected two arguments: <old version> <new version> (output documents or module directories)")
+	}
+
+	declared := LevelAdditive
+	if "" != *declaredBump {
+		var err error
+		declared, err = parseBump(*declaredBump)
+		if nil != err {
+			return err
+		}
+	}
+
+	before, err := loadSnapshot(flags.Arg(0))
+	if nil != err {
+		return err
+	}
+	after, err := loadSnapshot(flags.Arg(1))
+	if nil != err {
+		return err
+	}
+
+	beforeModule, found := before.Modules[*moduleName]
+	if !found {
+		return fmt.Errorf("module '%s' is not found in %s", *moduleName, flags.Arg(0))
+	}
+	afterModule, found := after.Modules[*moduleName]
+	if !found {
+		return fmt.Errorf("module '%s' is not found in %s", *moduleName, flags.Arg(1))
+	}
+
+	report := CheckCompatibility(*moduleName, beforeModule, afterModule)
+
+	var output []byte
+	if *jsonFormat {
+		output, err = json.Marshal(report)
+		if nil != err {
+			return err
+		}
+	} else {
+		var buffer bytes.Buffer
+		report.WriteText(&buffer)
+		output = buffer.Bytes()
+	}
+	err = writeOutput(output)
+	if nil != err {
+		return err
+	}
+
+	if LevelBreaking == report.Level && declared < LevelBreaking {
+		return fmt.Errorf("module %s has breaking changes, release must be a major version bump (use -bump=major)", *moduleName)
+	}
+	return nil
+}
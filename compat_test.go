@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	Convey("Interface changes must be classified and suggest a version bump", t, func() {
+		base := SnapshotModule{
+			Inputs: map[string]SnapshotInput{
+				"ami":  {Required: true, Edges: usagePathEdges("AsArgument", [][]string{{"aws_instance", "web", "ami"}})},
+				"size": {Required: false, Edges: usagePathEdges("AsArgument", [][]string{{"aws_instance", "web", "count"}})},
+			},
+			Outputs: map[string]SnapshotOutput{
+				"id": {Edges: usagePathEdges("FromAttribute", [][]string{{"aws_instance", "web", "id"}})},
+			},
+		}
+		with := func(change func(module *SnapshotModule)) SnapshotModule {
+			module := SnapshotModule{Inputs: make(map[string]SnapshotInput), Outputs: make(map[string]SnapshotOutput)}
+			for name, input := range base.Inputs {
+				module.Inputs[name] = input
+			}
+			for name, output := range base.Outputs {
+				module.Outputs[name] = output
+			}
+			change(&module)
+			return module
+		}
+
+		cases := []struct {
+			name   string
+			after  SnapshotModule
+			level  ChangeLevel
+			bump   string
+			kind   string
+			change string
+		}{
+			{
+				name:  "no changes",
+				after: with(func(module *SnapshotModule) {}),
+				level: LevelNone, bump: "none",
+			},
+			{
+				name:  "removed input",
+				after: with(func(module *SnapshotModule) { delete(module.Inputs, "size") }),
+				level: LevelBreaking, bump: "major", kind: "input", change: "size",
+			},
+			{
+				name:  "new required input",
+				after: with(func(module *SnapshotModule) { module.Inputs["subnet"] = SnapshotInput{Required: true} }),
+				level: LevelBreaking, bump: "major", kind: "input", change: "subnet",
+			},
+			{
+				name:  "new optional input",
+				after: with(func(module *SnapshotModule) { module.Inputs["subnet"] = SnapshotInput{Required: false} }),
+				level: LevelAdditive, bump: "minor", kind: "input", change: "subnet",
+			},
+			{
+				name: "input became required",
+				after: with(func(module *SnapshotModule) {
+					module.Inputs["size"] = SnapshotInput{Required: true, Edges: base.Inputs["size"].Edges}
+				}),
+				level: LevelBreaking, bump: "major", kind: "input", change: "size",
+			},
+			{
+				name: "input became optional",
+				after: with(func(module *SnapshotModule) {
+					module.Inputs["ami"] = SnapshotInput{Required: false, Edges: base.Inputs["ami"].Edges}
+				}),
+				level: LevelAdditive, bump: "minor", kind: "input", change: "ami",
+			},
+			{
+				name:  "removed output",
+				after: with(func(module *SnapshotModule) { delete(module.Outputs, "id") }),
+				level: LevelBreaking, bump: "major", kind: "output", change: "id",
+			},
+			{
+				name:  "new output",
+				after: with(func(module *SnapshotModule) { module.Outputs["arn"] = SnapshotOutput{} }),
+				level: LevelAdditive, bump: "minor", kind: "output", change: "arn",
+			},
+			{
+				name: "rewired input",
+				after: with(func(module *SnapshotModule) {
+					module.Inputs["ami"] = SnapshotInput{Required: true, Edges: usagePathEdges("AsArgument", [][]string{{"aws_launch_template", "web", "image_id"}})}
+				}),
+				level: LevelPatch, bump: "patch", kind: "input", change: "ami",
+			},
+		}
+
+		for _, c := range cases {
+			report := CheckCompatibility("modules.app", base, c.after)
+			So(report.Module, ShouldEqual, "modules.app")
+			So(report.Level, ShouldEqual, c.level)
+			So(report.Bump, ShouldEqual, c.bump)
+			if "" == c.change {
+				So(len(report.Changes), ShouldEqual, 0)
+				continue
+			}
+			So(len(report.Changes), ShouldEqual, 1)
+			So(report.Changes[0].Kind, ShouldEqual, c.kind)
+			So(report.Changes[0].Name, ShouldEqual, c.change)
+		}
+	})
+}
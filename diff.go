@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// ordered, name based view of the hierarchy, edges are rendered as strings like "AsArgument aws_instance.web.ami"
+type SnapshotInput struct {
+	Required bool
+	Edges    []string
+}
+
+type SnapshotOutput struct {
+	Edges []string
+}
+
+type SnapshotModule struct {
+	Inputs  map[string]SnapshotInput
+	Outputs map[string]SnapshotOutput
+}
+
+type HierarchySnapshot struct {
+	Modules map[string]SnapshotModule
+}
+
+func NewHierarchySnapshot(state *HierarchyState) *HierarchySnapshot {
+	snapshot := &HierarchySnapshot{Modules: make(map[string]SnapshotModule)}
+
+	for _, module := range state.AllModules {
+		snapshotModule := SnapshotModule{
+			Inputs:  make(map[string]SnapshotInput),
+			Outputs: make(map[string]SnapshotOutput),
+		}
+
+		for _, input := range module.Inputs {
+			var edges []string
+			for _, usage := range input.AsArgument {
+				edges = append(edges, usagePathEdges("AsArgument", usage.UsagePath)...)
+			}
+			for _, usage := range input.AsModuleInput {
+				edges = append(edges, usagePathEdges("AsModuleInput", usage.UsagePath)...)
+			}
+			snapshotModule.Inputs[input.Name] = SnapshotInput{Required: input.Required, Edges: sortedUnique(edges)}
+		}
+
+		for _, output := range module.Outputs {
+			var edges []string
+			for _, usage := range output.FromAttribute {
+				edges = append(edges, usagePathEdges("FromAttribute", usage.UsagePath)...)
+			}
+			for _, usage := range output.FromModuleOutput {
+				edges = append(edges, usagePathEdges("FromModuleOutput", usage.UsagePath)...)
+			}
+			snapshotModule.Outputs[output.Name] = SnapshotOutput{Edges: sortedUnique(edges)}
+		}
+
+		snapshot.Modules[module.Name] = snapshotModule
+	}
+	return snapshot
+}
+
+func usagePathEdges(kind string, usagePaths [][]string) []string {
+	result := make([]string, 0, len(usagePaths))
+	for _, path := range usagePaths {
+		result = append(result, kind+" "+strings.Join(Map(path, unquote), "."))
+	}
+	return result
+}
+
+func sortedUnique(vs []string) []string {
+	result := make([]string, 0, len(vs))
+	for _, v := range vs {
+		if !Include(result, v) {
+			result = append(result, v)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// diff
+type EdgeDiff struct {
+	Kind    string   `form:"Kind" json:"Kind" xml:"Kind"`
+	Name    string   `form:"Name" json:"Name" xml:"Name"`
+	Added   []string `form:"Added" json:"Added" xml:"Added"`
+	Removed []string `form:"Removed" json:"Removed" xml:"Removed"`
+}
+
+type RequiredDiff struct {
+	Input  string `form:"Input" json:"Input" xml:"Input"`
+	Before bool   `form:"Before" json:"Before" xml:"Before"`
+	After  bool   `form:"After" json:"After" xml:"After"`
+}
+
+type ModuleDiff struct {
+	Name            string         `form:"Name" json:"Name" xml:"Name"`
+	InputsAdded     []string       `form:"InputsAdded" json:"InputsAdded" xml:"InputsAdded"`
+	InputsRemoved   []string       `form:"InputsRemoved" json:"InputsRemoved" xml:"InputsRemoved"`
+	OutputsAdded    []string       `form:"OutputsAdded" json:"OutputsAdded" xml:"OutputsAdded"`
+	OutputsRemoved  []string       `form:"OutputsRemoved" json:"OutputsRemoved" xml:"OutputsRemoved"`
+	RequiredChanged []RequiredDiff `form:"RequiredChanged" json:"RequiredChanged" xml:"RequiredChanged"`
+	Edges           []EdgeDiff     `form:"Edges" json:"Edges" xml:"Edges"`
+}
+
+func (d ModuleDiff) IsEmpty() bool {
+	return 0 == len(d.InputsAdded)+len(d.InputsRemoved)+len(d.OutputsAdded)+len(d.OutputsRemoved)+len(d.RequiredChanged)+len(d.Edges)
+}
+
+type HierarchyDiff struct {
+	ModulesAdded   []string     `form:"ModulesAdded" json:"ModulesAdded" xml:"ModulesAdded"`
+	ModulesRemoved []string     `form:"ModulesRemoved" json:"ModulesRemoved" xml:"ModulesRemoved"`
+	Modules        []ModuleDiff `form:"Modules" json:"Modules" xml:"Modules"`
+}
+
+func (d *HierarchyDiff) IsEmpty() bool {
+	return 0 == len(d.ModulesAdded)+len(d.ModulesRemoved)+len(d.Modules)
+}
+
+func DiffSnapshots(before *HierarchySnapshot, after *HierarchySnapshot) *HierarchyDiff {
+	result := &HierarchyDiff{}
+
+	for _, name := range sortedKeys(before.Modules, after.Modules) {
+		beforeModule, inBefore := before.Modules[name]
+		afterModule, inAfter := after.Modules[name]
+		switch {
+		case !inAfter:
+			result.ModulesRemoved = append(result.ModulesRemoved, name)
+		case !inBefore:
+			result.ModulesAdded = append(result.ModulesAdded, name)
+		default:
+			moduleDiff := diffModules(name, beforeModule, afterModule)
+			if !moduleDiff.IsEmpty() {
+				result.Modules = append(result.Modules, moduleDiff)
+			}
+		}
+	}
+	return result
+}
+
+func diffModules(name string, before SnapshotModule, after SnapshotModule) ModuleDiff {
+	result := ModuleDiff{Name: name}
+
+	inputNames := make([]string, 0, len(before.Inputs)+len(after.Inputs))
+	for input := range before.Inputs {
+		inputNames = append(inputNames, input)
+	}
+	for input := range after.Inputs {
+		inputNames = append(inputNames, input)
+	}
+	for _, input := range sortedUnique(inputNames) {
+		beforeInput, inBefore := before.Inputs[input]
+		afterInput, inAfter := after.Inputs[input]
+		switch {
+		case !inAfter:
+			result.InputsRemoved = append(result.InputsRemoved, input)
+		case !inBefore:
+			result.InputsAdded = append(result.InputsAdded, input)
+		case beforeInput.Required != afterInput.Required:
+			result.RequiredChanged = append(result.RequiredChanged, RequiredDiff{Input: input, Before: beforeInput.Required, After: afterInput.Required})
+		}
+		if edges := diffEdges("input", input, beforeInput.Edges, afterInput.Edges); nil != edges {
+			result.Edges = append(result.Edges, *edges)
+		}
+	}
+
+	outputNames := make([]string, 0, len(before.Outputs)+len(after.Outputs))
+	for output := range before.Outputs {
+		outputNames = append(outputNames, output)
+	}
+	for output := range after.Outputs {
+		outputNames = append(outputNames, output)
+	}
+	for _, output := range sortedUnique(outputNames) {
+		beforeOutput, inBefore := before.Outputs[output]
+		afterOutput, inAfter := after.Outputs[output]
+		switch {
+		case !inAfter:
+			result.OutputsRemoved = append(result.OutputsRemoved, output)
+		case !inBefore:
+			result.OutputsAdded = append(result.OutputsAdded, output)
+		}
+		if edges := diffEdges("output", output, beforeOutput.Edges, afterOutput.Edges); nil != edges {
+			result.Edges = append(result.Edges, *edges)
+		}
+	}
+	return result
+}
+
+func diffEdges(kind string, name string, before []string, after []string) *EdgeDiff {
+	result := EdgeDiff{Kind: kind, Name: name}
+	for _, edge := range after {
+		if !Include(before, edge) {
+			result.Added = append(result.Added, edge)
+		}
+	}
+	for _, edge := range before {
+		if !Include(after, edge) {
+			result.Removed = append(result.Removed, edge)
+		}
+	}
+	if 0 == len(result.Added)+len(result.Removed) {
+		return nil
+	}
+	return &result
+}
+
+func sortedKeys(maps ...map[string]SnapshotModule) []string {
+	var keys []string
+	for _, m := range maps {
+		for key := range m {
+			keys = append(keys, key)
+		}
+	}
+	return sortedUnique(keys)
+}
+
+func (d *HierarchyDiff) WriteText(buffer *bytes.Buffer) {
+	for _, name := range d.ModulesAdded {
+		fmt.Fprintf(buffer, "+ module %s\n", name)
+	}
+	for _, name := range d.ModulesRemoved {
+		fmt.Fprintf(buffer, "- module %s\n", name)
+	}
+	for _, module := range d.Modules {
+		fmt.Fprintf(buffer, "~ module %s\n", module.Name)
+		for _, name := range module.InputsAdded {
+			fmt.Fprintf(buffer, "  + input %s\n", name)
+		}
+		for _, name := range module.InputsRemoved {
+			fmt.Fprintf(buffer, "  - input %s\n", name)
+		}
+		for _, name := range module.OutputsAdded {
+			fmt.Fprintf(buffer, "  + output %s\n", name)
+		}
+		for _, name := range module.OutputsRemoved {
+			fmt.Fprintf(buffer, "  - output %s\n", name)
+		}
+		for _, required := range module.RequiredChanged {
+			fmt.Fprintf(buffer, "  ~ input %s: %s -> %s\n", required.Input, requiredString(required.Before), requiredString(required.After))
+		}
+		for _, edges := range module.Edges {
+			for _, edge := range edges.Added {
+				fmt.Fprintf(buffer, "  + %s %s: %s\n", edges.Kind, edges.Name, edge)
+			}
+			for _, edge := range edges.Removed {
+				fmt.Fprintf(buffer, "  - %s %s: %s\n", edges.Kind, edges.Name, edge)
+			}
+		}
+	}
+}
+
+func requiredString(required bool) string {
+	if required {
+		return "required"
+	}
+	return "optional"
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// command
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	jsonFormat := flags.Bool("json", false, "print diff as json")
+	flags.Parse(args)
+
+	if 2 != flags.NArg() {
+		return fmt.Errorf("expected two arguments: <before> <after> (output documents or terraform directories)")
+	}
+
+	before, err := loadSnapshot(flags.Arg(0))
+	if nil != err {
+		return err
+	}
+	after, err := loadSnapshot(flags.Arg(1))
+	if nil != err {
+		return err
+	}
+
+	diff := DiffSnapshots(before, after)
+
+	if *jsonFormat {
+		jsonDiff, err := json.Marshal(diff)
+		if nil != err {
+			return err
+		}
+		return writeOutput(jsonDiff)
+	}
+
+	var buffer bytes.Buffer
+	diff.WriteText(&buffer)
+	return writeOutput(buffer.Bytes())
+}
+
+// loads either a json document written by dump or a terraform directory
+func loadSnapshot(path string) (*HierarchySnapshot, error) {
+	info, err := os.Stat(path)
+	if nil != err {
+		return nil, fmt.Errorf("snapshot loading: %v", err)
+	}
+
+	if !info.IsDir() {
+		bytes, err := ioutil.ReadFile(path)
+		if nil != err {
+			return nil, fmt.Errorf("snapshot loading: %v", err)
+		}
+		state := NewHierarchyState()
+		err = json.Unmarshal(bytes, state)
+		if nil != err {
+			return nil, fmt.Errorf("snapshot loading (%s): error unmarshalling hierarchy: %v", path, err)
+		}
+		return NewHierarchySnapshot(state), nil
+	}
+
+	var awsResources []Resource
+	if "" != *descriptionPath {
+		awsResources, err = loadResources(*descriptionPath)
+		if nil != err {
+			return nil, fmt.Errorf("error loading aws resources: %v", err)
+		}
+	}
+
+	state := NewHierarchyState()
+	err = loadModule(path, ".", awsResources, state)
+	if nil != err {
+		return nil, fmt.Errorf("snapshot loading (%s): %v", path, err)
+	}
+	return NewHierarchySnapshot(state), nil
+}
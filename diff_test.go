@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	Convey("Semantic diff must ignore ordering and report changes by name", t, func() {
+		before := &HierarchySnapshot{Modules: map[string]SnapshotModule{
+			".": {
+				Inputs: map[string]SnapshotInput{
+					"ami":  {Required: true, Edges: []string{"AsModuleInput app.image"}},
+					"size": {Required: false},
+				},
+				Outputs: map[string]SnapshotOutput{"ip": {Edges: []string{"FromModuleOutput app.ip"}}},
+			},
+			"modules.old": {},
+		}}
+		after := &HierarchySnapshot{Modules: map[string]SnapshotModule{
+			".": {
+				Inputs: map[string]SnapshotInput{
+					"ami":  {Required: false, Edges: []string{"AsModuleInput app.image", "AsModuleInput db.image"}},
+					"zone": {Required: true},
+				},
+				Outputs: map[string]SnapshotOutput{"ip": {Edges: []string{"FromModuleOutput app.ip"}}},
+			},
+			"modules.new": {},
+		}}
+
+		diff := DiffSnapshots(before, after)
+		So(diff.ModulesAdded, ShouldResemble, []string{"modules.new"})
+		So(diff.ModulesRemoved, ShouldResemble, []string{"modules.old"})
+		So(len(diff.Modules), ShouldEqual, 1)
+		So(diff.Modules[0].InputsAdded, ShouldResemble, []string{"zone"})
+		So(diff.Modules[0].InputsRemoved, ShouldResemble, []string{"size"})
+		So(diff.Modules[0].RequiredChanged, ShouldResemble, []RequiredDiff{{Input: "ami", Before: true, After: false}})
+		So(len(diff.Modules[0].Edges), ShouldEqual, 1)
+		So(diff.Modules[0].Edges[0].Added, ShouldResemble, []string{"AsModuleInput db.image"})
+
+		So(DiffSnapshots(after, after).IsEmpty(), ShouldBeTrue)
+
+		var buffer bytes.Buffer
+		diff.WriteText(&buffer)
+		So(buffer.String(), ShouldContainSubstring, "  ~ input ami: required -> optional\n")
+	})
+
+	Convey("Dumped state and loaded directory must produce the same snapshot", t, func() {
+		defer func(path string) { *descriptionPath = path }(*descriptionPath)
+		*descriptionPath = ""
+
+		dump, err := json.Marshal(loadTestState("testdata/plan"))
+		So(err, ShouldBeNil)
+		file, err := ioutil.TempFile("", "hierarchy-dump")
+		So(err, ShouldBeNil)
+		defer os.Remove(file.Name())
+		_, err = file.Write(dump)
+		So(err, ShouldBeNil)
+		So(file.Close(), ShouldBeNil)
+
+		dumped, err := loadSnapshot(file.Name())
+		So(err, ShouldBeNil)
+		loaded, err := loadSnapshot("testdata/plan")
+		So(err, ShouldBeNil)
+		So(len(loaded.Modules), ShouldEqual, 3)
+		So(DiffSnapshots(dumped, loaded).IsEmpty(), ShouldBeTrue)
+
+		app := dumped.Modules["modules.app"]
+		So(app.Inputs["image"].Required, ShouldBeTrue)
+		So(app.Inputs["image"].Edges, ShouldResemble, []string{"AsArgument aws_instance.web.ami"})
+	})
+}
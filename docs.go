@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// command
+func runDocs(args []string) error {
+	flags := flag.NewFlagSet("docs", flag.ExitOnError)
+	outDir := flags.String("out-dir", "docs", "directory to write markdown files to")
+	flags.Parse(args)
+
+	state, _, err := loadState()
+	if nil != err {
+		return err
+	}
+
+	err = os.MkdirAll(*outDir, 0755)
+	if nil != err {
+		return fmt.Errorf("docs: %v", err)
+	}
+
+	parents := moduleParents(state)
+	for _, module := range state.AllModules {
+		var buffer bytes.Buffer
+		writeModuleDoc(&buffer, state, module, parents[module.Name])
+
+		path := filepath.Join(*outDir, moduleDocFileName(module.Name))
+		log.Info("writing module documentation: ", path)
+		err = ioutil.WriteFile(path, buffer.Bytes(), 0644)
+		if nil != err {
+			return fmt.Errorf("docs: writing %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+func moduleDocFileName(name string) string {
+	if "." == name {
+		return "root.md"
+	}
+	return strings.Replace(name, string(filepath.Separator), "_", -1) + ".md"
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// markdown
+func writeModuleDoc(buffer *bytes.Buffer, state *HierarchyState, module *Module, parents []string) {
+	fmt.Fprintf(buffer, "# Module `%s`\n\n", module.Name)
+	if "" != module.Path {
+		fmt.Fprintf(buffer, "Path: `%s`\n\n", module.Path)
+	}
+	if !module.IsLoaded {
+		buffer.WriteString("Module source is not loaded, only its usage is known.\n\n")
+	}
+
+	inputs := sortedInputs(module.Inputs)
+	buffer.WriteString("## Inputs\n\n")
+	if 0 == len(inputs) {
+		buffer.WriteString("No inputs.\n\n")
+	} else {
+		buffer.WriteString("| Name | Type | Default | Required | Description |\n|------|------|---------|----------|-------------|\n")
+		for _, input := range inputs {
+			fmt.Fprintf(buffer, "| %s | %s | %s | %s | %s |\n", markdownCode(input.Name), markdownCode(input.Type),
+				markdownCode(input.Default), yesNo(input.Required), markdownCell(input.Description))
+		}
+		buffer.WriteString("\n")
+	}
+
+	outputs := sortedOutputs(module.Outputs)
+	buffer.WriteString("## Outputs\n\n")
+	if 0 == len(outputs) {
+		buffer.WriteString("No outputs.\n\n")
+	} else {
+		buffer.WriteString("| Name | Description | Sources |\n|------|-------------|---------|\n")
+		for _, output := range outputs {
+			var sources []string
+			for _, usage := range output.FromAttribute {
+				for _, path := range usage.UsagePath {
+					sources = append(sources, strings.Join(Map(path, unquote), "."))
+				}
+			}
+			for _, usage := range output.FromModuleOutput {
+				for _, path := range usage.UsagePath {
+					sources = append(sources, "module."+strings.Join(Map(path, unquote), "."))
+				}
+			}
+			fmt.Fprintf(buffer, "| %s | %s | %s |\n", markdownCode(output.Name), markdownCell(output.Description),
+				markdownCell(strings.Join(sortedUnique(sources), ", ")))
+		}
+		buffer.WriteString("\n")
+	}
+
+	buffer.WriteString("## Module calls\n\n")
+	if 0 == len(module.ModuleInstances) {
+		buffer.WriteString("No module calls.\n\n")
+	} else {
+		buffer.WriteString("| Instance | Module |\n|----------|--------|\n")
+		for _, instance := range module.ModuleInstances {
+			fmt.Fprintf(buffer, "| %s | [%s](%s) |\n", markdownCode(instance.InstanceName), instance.ModulePath, moduleDocFileName(instance.ModulePath))
+		}
+		buffer.WriteString("\n")
+	}
+
+	buffer.WriteString("## Resources\n\n")
+	if 0 == len(module.Resources) {
+		buffer.WriteString("No managed resources.\n\n")
+	} else {
+		buffer.WriteString("| Type | Name |\n|------|------|\n")
+		for _, resource := range module.Resources {
+			fmt.Fprintf(buffer, "| %s | %s |\n", markdownCode(resource.Type), markdownCode(resource.Name))
+		}
+		buffer.WriteString("\n")
+	}
+
+	buffer.WriteString("## Used by\n\n")
+	if 0 == len(parents) {
+		buffer.WriteString("Not used by other modules.\n\n")
+	} else {
+		for _, parentName := range parents {
+			parent, found := state.allModulesMap[parentName]
+			if !found {
+				continue
+			}
+			for _, instance := range parent.ModuleInstances {
+				if instance.ModulePath == module.Name {
+					fmt.Fprintf(buffer, "* [%s](%s) as `module.%s`\n", parent.Name, moduleDocFileName(parent.Name), instance.InstanceName)
+				}
+			}
+		}
+		buffer.WriteString("\n")
+	}
+
+	buffer.WriteString("## Feeds\n\n")
+	feeds := false
+	for _, input := range inputs {
+		flows := findInputFlows(state, module, input, nil)
+		if 0 == len(flows) {
+			continue
+		}
+		if !feeds {
+			buffer.WriteString("| Input | Resource argument | Description |\n|-------|-------------------|-------------|\n")
+			feeds = true
+		}
+		for _, flow := range flows {
+			description := ""
+			if nil != flow.Argument {
+				description = flow.Argument.Description
+			}
+			fmt.Fprintf(buffer, "| %s | %s | %s |\n", markdownCode(input.Name), markdownCode(flow.String()), markdownCell(description))
+		}
+	}
+	if !feeds {
+		buffer.WriteString("Inputs do not reach any resource argument.\n")
+	} else {
+		buffer.WriteString("\n")
+	}
+}
+
+// resource argument reached by an input, possibly through nested module instances
+type inputFlow struct {
+	Instances []string
+	Path      []string
+	Argument  *ResourceArgument
+}
+
+func (f inputFlow) String() string {
+	var parts []string
+	for _, instance := range f.Instances {
+		parts = append(parts, "module."+instance)
+	}
+	return strings.Join(append(parts, strings.Join(f.Path, ".")), ".")
+}
+
+func findInputFlows(state *HierarchyState, module *Module, input *ModuleInput, instances []string) []inputFlow {
+	var result []inputFlow
+	for _, usage := range input.AsArgument {
+		for _, path := range usage.UsagePath {
+			result = append(result, inputFlow{Instances: instances, Path: Map(path, unquote), Argument: usage.Arg})
+		}
+	}
+
+	for _, usage := range input.AsModuleInput {
+		for _, path := range usage.UsagePath {
+			if len(path) < 2 {
+				continue
+			}
+			instance := module.FindModuleInstance(path[0])
+			if nil == instance {
+				continue
+			}
+			child, found := state.allModulesMap[instance.ModulePath]
+			if !found || Include(instances, path[0]) {
+				continue
+			}
+			for _, childInput := range child.Inputs {
+				if childInput.Name == unquote(path[1]) {
+					childInstances := append(append([]string{}, instances...), path[0])
+					result = append(result, findInputFlows(state, child, childInput, childInstances)...)
+				}
+			}
+		}
+	}
+	return result
+}
+
+func sortedInputs(inputs []*ModuleInput) []*ModuleInput {
+	result := append([]*ModuleInput{}, inputs...)
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+func sortedOutputs(outputs []*ModuleOutput) []*ModuleOutput {
+	result := append([]*ModuleOutput{}, outputs...)
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+func markdownCell(s string) string {
+	s = strings.Replace(s, "|", "\\|", -1)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func markdownCode(s string) string {
+	if "" == s {
+		return ""
+	}
+	return "`" + strings.Replace(markdownCell(s), "`", "'", -1) + "`"
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestModuleDocs(t *testing.T) {
+	Convey("Module documentation files must be named after modules", t, func() {
+		So(moduleDocFileName("."), ShouldEqual, "root.md")
+		So(moduleDocFileName("modules.app"), ShouldEqual, "modules.app.md")
+		So(moduleDocFileName(filepath.Join("live", "app")), ShouldEqual, "live_app.md")
+	})
+
+	Convey("Inputs must be followed into the resource arguments of nested modules", t, func() {
+		state := loadTestState("testdata/docs")
+		root := state.allModulesMap["."]
+
+		var flows []string
+		for _, input := range sortedInputs(root.Inputs) {
+			for _, flow := range findInputFlows(state, root, input, nil) {
+				flows = append(flows, input.Name+" -> "+flow.String())
+			}
+		}
+		So(flows, ShouldResemble, []string{
+			"ami -> module.app.aws_instance.web.ami",
+			"disk_size -> module.app.module.volume.aws_ebs_volume.data.size",
+		})
+	})
+
+	Convey("Module documentation must match the golden file", t, func() {
+		state := loadTestState("testdata/docs")
+		var buffer bytes.Buffer
+		writeModuleDoc(&buffer, state, state.allModulesMap["modules.app"], moduleParents(state)["modules.app"])
+
+		golden, err := ioutil.ReadFile("testdata/docs/modules.app.md")
+		So(err, ShouldBeNil)
+		So(buffer.String(), ShouldEqual, string(golden))
+	})
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// data flow graph: edges go in the direction values flow (input -> resource argument -> ... -> output)
+type FlowNode struct {
+	ID     string    `form:"ID" json:"ID" xml:"ID"`
+	Kind   string    `form:"Kind" json:"Kind" xml:"Kind"`
+	Module string    `form:"Module" json:"Module" xml:"Module"`
+	Name   string    `form:"Name" json:"Name" xml:"Name"`
+	Pos    SourcePos `form:"Pos" json:"Pos" xml:"Pos"`
+}
+
+type FlowEdge struct {
+	From  string `form:"From" json:"From" xml:"From"`
+	To    string `form:"To" json:"To" xml:"To"`
+	Kind  string `form:"Kind" json:"Kind" xml:"Kind"`
+	Label string `form:"Label" json:"Label" xml:"Label"`
+}
+
+type FlowGraph struct {
+	Nodes []FlowNode `form:"Nodes" json:"Nodes" xml:"Nodes"`
+	Edges []FlowEdge `form:"Edges" json:"Edges" xml:"Edges"`
+
+	nodes    map[string]int
+	outgoing map[string][]int
+	incoming map[string][]int
+}
+
+func moduleNodeID(module string) string {
+	return "module:" + module
+}
+
+func inputNodeID(module string, name string) string {
+	return "input:" + module + ":" + name
+}
+
+func outputNodeID(module string, name string) string {
+	return "output:" + module + ":" + name
+}
+
+func localNodeID(module string, name string) string {
+	return "local:" + module + ":" + name
+}
+
+func resourceNodeID(module string, resourceType string, name string) string {
+	return "resource:" + module + ":" + resourceType + "." + name
+}
+
+func NewFlowGraph(state *HierarchyState) *FlowGraph {
+	g := &FlowGraph{nodes: make(map[string]int), outgoing: make(map[string][]int), incoming: make(map[string][]int)}
+
+	for _, module := range state.AllModules {
+		g.addNode(FlowNode{ID: moduleNodeID(module.Name), Kind: "module", Module: module.Name, Name: module.Name})
+		for _, input := range module.Inputs {
+			g.addNode(FlowNode{ID: inputNodeID(module.Name, input.Name), Kind: "input", Module: module.Name, Name: input.Name, Pos: input.Pos})
+		}
+		for _, output := range module.Outputs {
+			g.addNode(FlowNode{ID: outputNodeID(module.Name, output.Name), Kind: "output", Module: module.Name, Name: output.Name, Pos: output.Pos})
+		}
+		for _, resource := range module.Resources {
+			g.addNode(FlowNode{ID: resourceNodeID(module.Name, resource.Type, resource.Name), Kind: "resource", Module: module.Name, Name: resource.Type + "." + resource.Name, Pos: resource.Pos})
+		}
+		for _, local := range module.Locals {
+			g.addNode(FlowNode{ID: localNodeID(module.Name, local.Name), Kind: "local", Module: module.Name, Name: local.Name, Pos: local.Pos})
+		}
+	}
+
+	for _, module := range state.AllModules {
+		for _, input := range module.Inputs {
+			from := inputNodeID(module.Name, input.Name)
+			for _, usage := range input.AsArgument {
+				for _, path := range usage.UsagePath {
+					if len(path) < 3 {
+						continue
+					}
+					to := g.resourceNode(module.Name, unquote(path[0]), unquote(path[1]))
+					g.addEdge(FlowEdge{From: from, To: to, Kind: "AsArgument", Label: unquote(path[2])})
+				}
+			}
+			for _, usage := range input.AsModuleInput {
+				for _, path := range usage.UsagePath {
+					if len(path) < 2 {
+						continue
+					}
+					instance := module.FindModuleInstance(path[0])
+					if nil == instance {
+						continue
+					}
+					to := g.inputNode(instance.ModulePath, unquote(path[1]))
+					g.addEdge(FlowEdge{From: from, To: to, Kind: "AsModuleInput", Label: "module." + path[0]})
+				}
+			}
+		}
+
+		for _, output := range module.Outputs {
+			to := outputNodeID(module.Name, output.Name)
+			for _, name := range output.FromInput {
+				g.addEdge(FlowEdge{From: g.inputNode(module.Name, name), To: to, Kind: "FromInput", Label: "var." + name})
+			}
+			for _, usage := range output.FromAttribute {
+				for _, path := range usage.UsagePath {
+					if len(path) < 3 {
+						continue
+					}
+					from := g.resourceNode(module.Name, path[0], path[1])
+					g.addEdge(FlowEdge{From: from, To: to, Kind: "FromAttribute", Label: path[2]})
+				}
+			}
+			for _, usage := range output.FromModuleOutput {
+				for _, path := range usage.UsagePath {
+					if len(path) < 2 {
+						continue
+					}
+					instance := module.FindModuleInstance(path[0])
+					if nil == instance {
+						continue
+					}
+					from := g.outputNode(instance.ModulePath, path[1])
+					g.addEdge(FlowEdge{From: from, To: to, Kind: "FromModuleOutput", Label: "module." + path[0]})
+				}
+			}
+		}
+	}
+
+	for _, module := range state.AllModules {
+		g.addLocalEdges(module)
+	}
+
+	// outputs of other roots read through remote state
+	for _, link := range LinkRemoteStates(state) {
+		module, found := state.allModulesMap[link.Module]
+		if !found || "" == link.SourceRoot {
+			continue
+		}
+		label := "data.terraform_remote_state." + link.RemoteState
+		for _, output := range link.Outputs {
+			from := g.outputNode(link.SourceRoot, output.Output)
+			for _, usage := range output.Usages {
+				path := usage.UsagePath
+				switch {
+				case "argument" == usage.Kind && len(path) >= 3:
+					g.addEdge(FlowEdge{From: from, To: g.resourceNode(module.Name, path[0], path[1]), Kind: "FromRemoteState", Label: label})
+				case "module-input" == usage.Kind && len(path) >= 2:
+					if instance := module.FindModuleInstance(path[0]); nil != instance {
+						g.addEdge(FlowEdge{From: from, To: g.inputNode(instance.ModulePath, unquote(path[1])), Kind: "FromRemoteState", Label: label})
+					}
+				case "output" == usage.Kind && len(path) >= 1:
+					g.addEdge(FlowEdge{From: from, To: g.outputNode(module.Name, path[0]), Kind: "FromRemoteState", Label: label})
+				}
+			}
+		}
+	}
+
+	// outputs of other terragrunt units read through dependency blocks
+	for _, link := range LinkTerragruntDependencies(state) {
+		if "" != link.Error {
+			continue
+		}
+		unit := state.allModulesMap[link.Unit]
+		from := g.outputNode(link.SourceModule, link.Output)
+		to := g.inputNode(unit.Terragrunt.SourceModule, link.Input)
+		g.addEdge(FlowEdge{From: from, To: to, Kind: "FromDependency", Label: "dependency." + link.Dependency})
+	}
+
+	g.sort()
+	return g
+}
+
+// values flowing into a local come from its expression, usages say where the local flows to
+func (g *FlowGraph) addLocalEdges(module *Module) {
+	for _, local := range module.Locals {
+		to := g.localNode(module.Name, local.Name)
+		for _, variable := range findAllVariables(local.Value) {
+			g.addEdge(FlowEdge{From: g.inputNode(module.Name, string(variable)), To: to, Kind: "AsLocal", Label: "var." + string(variable)})
+		}
+		for _, field := range findAllResourceFields(local.Value) {
+			g.addEdge(FlowEdge{From: g.resourceNode(module.Name, field.Name, field.InstanceName), To: to, Kind: "FromAttribute", Label: field.FieldName})
+		}
+		for _, field := range findAllModuleFields(local.Value) {
+			if instance := module.FindModuleInstance(field.InstanceName); nil != instance {
+				g.addEdge(FlowEdge{From: g.outputNode(instance.ModulePath, field.FieldName), To: to, Kind: "FromModuleOutput", Label: "module." + field.InstanceName})
+			}
+		}
+
+		from := to
+		label := "local." + local.Name
+		for _, usage := range local.Usages {
+			path := usage.UsagePath
+			switch {
+			case "argument" == usage.Kind && len(path) >= 3:
+				g.addEdge(FlowEdge{From: from, To: g.resourceNode(module.Name, path[0], path[1]), Kind: "AsArgument", Label: unquote(path[2])})
+			case "module-input" == usage.Kind && len(path) >= 2:
+				if instance := module.FindModuleInstance(path[0]); nil != instance {
+					g.addEdge(FlowEdge{From: from, To: g.inputNode(instance.ModulePath, unquote(path[1])), Kind: "AsModuleInput", Label: "module." + path[0]})
+				}
+			case "output" == usage.Kind && len(path) >= 1:
+				g.addEdge(FlowEdge{From: from, To: g.outputNode(module.Name, path[0]), Kind: "FromLocal", Label: label})
+			case "local" == usage.Kind && len(path) >= 1:
+				g.addEdge(FlowEdge{From: from, To: g.localNode(module.Name, path[0]), Kind: "FromLocal", Label: label})
+			}
+		}
+	}
+}
+
+func (g *FlowGraph) addNode(node FlowNode) {
+	if _, found := g.nodes[node.ID]; found {
+		return
+	}
+	g.nodes[node.ID] = len(g.Nodes)
+	g.Nodes = append(g.Nodes, node)
+}
+
+// nodes referenced by edges but not declared are added on demand
+func (g *FlowGraph) inputNode(module string, name string) string {
+	id := inputNodeID(module, name)
+	g.addNode(FlowNode{ID: id, Kind: "input", Module: module, Name: name})
+	return id
+}
+
+func (g *FlowGraph) outputNode(module string, name string) string {
+	id := outputNodeID(module, name)
+	g.addNode(FlowNode{ID: id, Kind: "output", Module: module, Name: name})
+	return id
+}
+
+func (g *FlowGraph) localNode(module string, name string) string {
+	id := localNodeID(module, name)
+	g.addNode(FlowNode{ID: id, Kind: "local", Module: module, Name: name})
+	return id
+}
+
+func (g *FlowGraph) resourceNode(module string, resourceType string, name string) string {
+	id := resourceNodeID(module, resourceType, name)
+	g.addNode(FlowNode{ID: id, Kind: "resource", Module: module, Name: resourceType + "." + name})
+	return id
+}
+
+func (g *FlowGraph) addEdge(edge FlowEdge) {
+	for _, i := range g.outgoing[edge.From] {
+		if g.Edges[i] == edge {
+			return
+		}
+	}
+	g.outgoing[edge.From] = append(g.outgoing[edge.From], len(g.Edges))
+	g.incoming[edge.To] = append(g.incoming[edge.To], len(g.Edges))
+	g.Edges = append(g.Edges, edge)
+}
+
+// deterministic order of nodes and edges, indexes are rebuilt
+func (g *FlowGraph) sort() {
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		return strings.Join([]string{a.From, a.To, a.Kind, a.Label}, "\x00") < strings.Join([]string{b.From, b.To, b.Kind, b.Label}, "\x00")
+	})
+
+	g.nodes = make(map[string]int)
+	g.outgoing = make(map[string][]int)
+	g.incoming = make(map[string][]int)
+	for i, node := range g.Nodes {
+		g.nodes[node.ID] = i
+	}
+	for i, edge := range g.Edges {
+		g.outgoing[edge.From] = append(g.outgoing[edge.From], i)
+		g.incoming[edge.To] = append(g.incoming[edge.To], i)
+	}
+}
+
+func (g *FlowGraph) Node(id string) (FlowNode, bool) {
+	i, found := g.nodes[id]
+	if !found {
+		return FlowNode{}, false
+	}
+	return g.Nodes[i], true
+}
+
+// edges reachable from the node following the flow direction
+func (g *FlowGraph) Downstream(id string) []FlowEdge {
+	return g.walk(id, g.outgoing, func(edge FlowEdge) string { return edge.To })
+}
+
+// edges the node value is computed from
+func (g *FlowGraph) Upstream(id string) []FlowEdge {
+	return g.walk(id, g.incoming, func(edge FlowEdge) string { return edge.From })
+}
+
+func (g *FlowGraph) walk(id string, index map[string][]int, next func(FlowEdge) string) []FlowEdge {
+	var result []FlowEdge
+	visited := map[string]bool{id: true}
+	queue := []string{id}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, i := range index[current] {
+			edge := g.Edges[i]
+			result = append(result, edge)
+			if nextID := next(edge); !visited[nextID] {
+				visited[nextID] = true
+				queue = append(queue, nextID)
+			}
+		}
+	}
+	return result
+}
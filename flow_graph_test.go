@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFlowGraph(t *testing.T) {
+	Convey("Flow graph must connect inputs across module boundaries", t, func() {
+		graph := NewFlowGraph(loadTestState("testdata/plan"))
+
+		_, found := graph.Node(inputNodeID(".", "ami"))
+		So(found, ShouldBeTrue)
+
+		down := graph.Downstream(inputNodeID(".", "ami"))
+		So(len(down), ShouldEqual, 2)
+		So(down[0].To, ShouldEqual, inputNodeID("modules.app", "image"))
+		So(down[1].To, ShouldEqual, resourceNodeID("modules.app", "aws_instance", "web"))
+		So(down[1].Label, ShouldEqual, "ami")
+
+		up := graph.Upstream(resourceNodeID("modules.app", "aws_instance", "web"))
+		So(len(up), ShouldEqual, 4)
+	})
+}
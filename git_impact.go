@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// git diff representation
+type changedFile struct {
+	OldPath string
+	NewPath string
+	// changed line ranges on the new side, deletions are represented by the surrounding lines
+	Lines []SourcePos
+}
+
+func (f changedFile) IsDeleted() bool {
+	return "" == f.NewPath
+}
+
+func (f changedFile) ContainsAny(pos SourcePos) bool {
+	for _, lines := range f.Lines {
+		if lines.Line <= pos.EndLine && pos.Line <= lines.EndLine {
+			return true
+		}
+	}
+	return false
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// impact report
+type ChangedElement struct {
+	Module string `form:"Module" json:"Module" xml:"Module"`
+	Kind   string `form:"Kind" json:"Kind" xml:"Kind"`
+	Name   string `form:"Name" json:"Name" xml:"Name"`
+	File   string `form:"File" json:"File" xml:"File"`
+	Line   int    `form:"Line" json:"Line" xml:"Line"`
+}
+
+type ChangeImpactReport struct {
+	Base            string           `form:"Base" json:"Base" xml:"Base"`
+	Head            string           `form:"Head" json:"Head" xml:"Head"`
+	Changes         []ChangedElement `form:"Changes" json:"Changes" xml:"Changes"`
+	AffectedModules []string         `form:"AffectedModules" json:"AffectedModules" xml:"AffectedModules"`
+	AffectedRoots   []string         `form:"AffectedRoots" json:"AffectedRoots" xml:"AffectedRoots"`
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// command
+func runImpact(args []string) error {
+	flags := flag.NewFlagSet("impact", flag.ExitOnError)
+	base := flags.String("base", "", "git ref to compare with")
+	head := flags.String("head", "", "git ref with changes, must be checked out (working tree by default)")
+	listRoots := flags.Bool("list-roots", false, "print only affected root module paths, one per line")
+	flags.Parse(args)
+
+	if "" == *base {
+		return fmt.Errorf("base ref must be set with -base")
+	}
+	// changed lines of the head are matched against positions read from the working tree
+	if "" != *head {
+		if _, err := runGit(*rootDir, "diff", "--quiet", "--no-ext-diff", *head, "--", "."); nil != err {
+			return fmt.Errorf("working tree differs from -head %s, check it out or leave -head unset: %v", *head, err)
+		}
+	}
+
+	files, err := gitChangedFiles(*rootDir, *base, *head)
+	if nil != err {
+		return err
+	}
+
+	state, _, err := loadState()
+	if nil != err {
+		return err
+	}
+
+	report := AnalyzeChangeImpact(state, files)
+	report.Base = *base
+	report.Head = *head
+
+	if *listRoots {
+		var buffer bytes.Buffer
+		for _, root := range report.AffectedRoots {
+			buffer.WriteString(root + "\n")
+		}
+		return writeOutput(buffer.Bytes())
+	}
+
+	jsonReport, err := json.Marshal(report)
+	if nil != err {
+		return err
+	}
+	return writeOutput(jsonReport)
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// git
+func gitChangedFiles(dir string, base string, head string) ([]changedFile, error) {
+	topLevel, err := runGit(dir, "rev-parse", "--show-toplevel")
+	if nil != err {
+		return nil, err
+	}
+	topLevel = strings.TrimSpace(topLevel)
+
+	args := []string{"diff", "--unified=0", "--no-color", "--no-ext-diff", "--no-renames", base}
+	if "" != head {
+		args = append(args, head)
+	}
+	diff, err := runGit(dir, args...)
+	if nil != err {
+		return nil, err
+	}
+
+	files, err := parseGitDiff(diff)
+	if nil != err {
+		return nil, err
+	}
+
+	for i := range files {
+		if "" != files[i].OldPath {
+			files[i].OldPath = filepath.Join(topLevel, files[i].OldPath)
+		}
+		if "" != files[i].NewPath {
+			files[i].NewPath = filepath.Join(topLevel, files[i].NewPath)
+		}
+	}
+	return files, nil
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if nil != err {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+var hunkHeaderRegexp = regexp.MustCompile("^@@ -([0-9]+)(?:,([0-9]+))? \\+([0-9]+)(?:,([0-9]+))? @@")
+
+// parses output of 'git diff --unified=0', only terraform files are kept
+func parseGitDiff(diff string) ([]changedFile, error) {
+	var files []changedFile
+	var current *changedFile
+
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			files = append(files, changedFile{})
+			current = &files[len(files)-1]
+		case nil == current:
+			continue
+		case strings.HasPrefix(line, "--- "):
+			current.OldPath = diffPath(line[4:], "a/")
+		case strings.HasPrefix(line, "+++ "):
+			current.NewPath = diffPath(line[4:], "b/")
+		case strings.HasPrefix(line, "@@ "):
+			matches := hunkHeaderRegexp.FindStringSubmatch(line)
+			if nil == matches {
+				return nil, fmt.Errorf("git diff: malformed hunk header '%s'", line)
+			}
+			start, _ := strconv.Atoi(matches[3])
+			count := 1
+			if "" != matches[4] {
+				count, _ = strconv.Atoi(matches[4])
+			}
+			if 0 == count {
+				// pure deletion after line 'start'
+				current.Lines = append(current.Lines, SourcePos{Line: start, EndLine: start + 1})
+			} else {
+				current.Lines = append(current.Lines, SourcePos{Line: start, EndLine: start + count - 1})
+			}
+		}
+	}
+	if err := scanner.Err(); nil != err {
+		return nil, fmt.Errorf("git diff: %v", err)
+	}
+
+	return filterChangedFiles(files), nil
+}
+
+func diffPath(path string, prefix string) string {
+	if "/dev/null" == path {
+		return ""
+	}
+	return strings.TrimPrefix(unquote(path), prefix)
+}
+
+func filterChangedFiles(files []changedFile) []changedFile {
+	result := make([]changedFile, 0, len(files))
+	for _, file := range files {
+		if isTerraformFile(file.NewPath) || isTerraformFile(file.OldPath) {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+func isTerraformFile(path string) bool {
+	return "" != path && isModuleFileName(path)
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// analysis
+func AnalyzeChangeImpact(state *HierarchyState, files []changedFile) *ChangeImpactReport {
+	report := &ChangeImpactReport{}
+
+	changedModules := make(map[string]bool)
+	changedInputs := make(map[string][]string)
+
+	for _, file := range files {
+		module := findFileModule(state, file)
+		if nil == module {
+			log.Warningf("impact: file %s does not belong to any loaded module", file.NewPath+file.OldPath)
+			continue
+		}
+		changedModules[module.Name] = true
+
+		elements := findChangedElements(module, file)
+		if 0 == len(elements) {
+			path := file.NewPath
+			if file.IsDeleted() {
+				path = file.OldPath
+			}
+			elements = append(elements, ChangedElement{Module: module.Name, Kind: "file", Name: filepath.Base(path), File: path})
+		}
+		for _, element := range elements {
+			if "variable" == element.Kind {
+				changedInputs[module.Name] = append(changedInputs[module.Name], element.Name)
+			}
+		}
+		report.Changes = append(report.Changes, elements...)
+	}
+
+	affected := make(map[string]bool)
+	for name := range changedModules {
+		markDownstreamModules(state, name, changedInputs[name], affected)
+	}
+
+	parents := moduleParents(state)
+	for name := range changedModules {
+		markAncestorModules(name, parents, affected)
+	}
+
+	for name := range affected {
+		report.AffectedModules = append(report.AffectedModules, name)
+		if 0 == len(parents[name]) {
+			if module, found := state.allModulesMap[name]; found && module.IsLoaded {
+				report.AffectedRoots = append(report.AffectedRoots, module.Path)
+			}
+		}
+	}
+	sort.Strings(report.AffectedModules)
+	sort.Strings(report.AffectedRoots)
+
+	return report
+}
+
+func findFileModule(state *HierarchyState, file changedFile) *Module {
+	path := file.NewPath
+	if file.IsDeleted() {
+		path = file.OldPath
+	}
+	return findDirModule(state, *rootDir, filepath.Dir(path))
+}
+
+func findChangedElements(module *Module, file changedFile) []ChangedElement {
+	var result []ChangedElement
+	if file.IsDeleted() {
+		return result
+	}
+
+	matches := func(pos SourcePos) bool {
+		filename, err := filepath.Abs(pos.Filename)
+		return nil == err && filename == file.NewPath && file.ContainsAny(pos)
+	}
+
+	for _, input := range module.Inputs {
+		if matches(input.Pos) {
+			result = append(result, ChangedElement{Module: module.Name, Kind: "variable", Name: input.Name, File: input.Pos.Filename, Line: input.Pos.Line})
+		}
+	}
+	for _, output := range module.Outputs {
+		if matches(output.Pos) {
+			result = append(result, ChangedElement{Module: module.Name, Kind: "output", Name: output.Name, File: output.Pos.Filename, Line: output.Pos.Line})
+		}
+	}
+	for _, resource := range module.Resources {
+		if matches(resource.Pos) {
+			result = append(result, ChangedElement{Module: module.Name, Kind: "resource", Name: resource.Type + "." + resource.Name, File: resource.Pos.Filename, Line: resource.Pos.Line})
+		}
+	}
+	for _, instance := range module.ModuleInstances {
+		if matches(instance.Pos) {
+			result = append(result, ChangedElement{Module: module.Name, Kind: "module", Name: instance.InstanceName, File: instance.Pos.Filename, Line: instance.Pos.Line})
+		}
+	}
+	return result
+}
+
+func markAncestorModules(name string, parents map[string][]string, affected map[string]bool) {
+	affected[name] = true
+	for _, parent := range parents[name] {
+		if !affected[parent] {
+			markAncestorModules(parent, parents, affected)
+		}
+	}
+}
+
+// follows AsModuleInput edges of changed inputs into child modules
+func markDownstreamModules(state *HierarchyState, name string, inputs []string, affected map[string]bool) {
+	affected[name] = true
+
+	module, found := state.allModulesMap[name]
+	if !found {
+		return
+	}
+
+	for _, input := range module.Inputs {
+		if !Include(inputs, input.Name) {
+			continue
+		}
+		for _, usage := range input.AsModuleInput {
+			for _, path := range usage.UsagePath {
+				if len(path) < 2 {
+					continue
+				}
+				instance := module.FindModuleInstance(path[0])
+				if nil == instance {
+					continue
+				}
+				childInputs := []string{unquote(path[1])}
+				markDownstreamModules(state, instance.ModulePath, childInputs, affected)
+			}
+		}
+	}
+}
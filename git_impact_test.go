@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseGitDiff(t *testing.T) {
+	Convey("Hunks of git diff must become changed line ranges of the new side", t, func() {
+		files, err := parseGitDiff(`diff --git a/app/main.tf b/app/main.tf
+index 1111111..2222222 100644
+--- a/app/main.tf
++++ b/app/main.tf
+@@ -3 +3 @@ variable "a" {}
+-  default = 1
++  default = 2
+@@ -10,0 +11,3 @@ resource "aws_instance" "web" {
++output "id" {
++  value = "${aws_instance.web.id}"
++}
+@@ -20,2 +23,0 @@
+-variable "old" {}
+-
+diff --git a/README.md b/README.md
+--- a/README.md
++++ b/README.md
+@@ -1 +1 @@
+-a
++b
+diff --git a/new.tf.json b/new.tf.json
+new file mode 100644
+--- /dev/null
++++ b/new.tf.json
+@@ -0,0 +1,2 @@
++{
++}
+diff --git a/gone.tf b/gone.tf
+deleted file mode 100644
+--- a/gone.tf
++++ /dev/null
+@@ -1 +0,0 @@
+-variable "x" {}
+diff --git "a/with space.tf" "b/with space.tf"
+--- "a/with space.tf"
++++ "b/with space.tf"
+@@ -5,4 +5,2 @@
+`)
+		So(err, ShouldBeNil)
+		So(len(files), ShouldEqual, 4)
+
+		So(files[0].OldPath, ShouldEqual, "app/main.tf")
+		So(files[0].NewPath, ShouldEqual, "app/main.tf")
+		So(files[0].Lines, ShouldResemble, []SourcePos{{Line: 3, EndLine: 3}, {Line: 11, EndLine: 13}, {Line: 23, EndLine: 24}})
+
+		So(files[1].OldPath, ShouldEqual, "")
+		So(files[1].NewPath, ShouldEqual, "new.tf.json")
+		So(files[1].Lines, ShouldResemble, []SourcePos{{Line: 1, EndLine: 2}})
+
+		So(files[2].IsDeleted(), ShouldBeTrue)
+		So(files[2].OldPath, ShouldEqual, "gone.tf")
+
+		So(files[3].NewPath, ShouldEqual, "with space.tf")
+		So(files[3].Lines, ShouldResemble, []SourcePos{{Line: 5, EndLine: 6}})
+
+		So(files[0].ContainsAny(SourcePos{Line: 9, EndLine: 12}), ShouldBeTrue)
+		So(files[0].ContainsAny(SourcePos{Line: 4, EndLine: 10}), ShouldBeFalse)
+		So(files[0].ContainsAny(SourcePos{Line: 24, EndLine: 30}), ShouldBeTrue)
+	})
+
+	Convey("Malformed hunk headers must fail", t, func() {
+		_, err := parseGitDiff("diff --git a/main.tf b/main.tf\n--- a/main.tf\n+++ b/main.tf\n@@ -1 +x @@\n")
+		So(err, ShouldNotBeNil)
+	})
+}
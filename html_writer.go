@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"sort"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////
+// self contained html explorer, all data and scripts are inlined
+type htmlInstance struct {
+	Name   string
+	Module string
+}
+
+type htmlModule struct {
+	Name      string
+	Path      string
+	IsLoaded  bool
+	IsRoot    bool
+	Instances []htmlInstance
+}
+
+type htmlPage struct {
+	Title   string
+	Modules []htmlModule
+	Graph   *FlowGraph
+}
+
+func WriteHTML(state *HierarchyState, title string) ([]byte, error) {
+	parents := moduleParents(state)
+
+	page := htmlPage{Title: title, Graph: NewFlowGraph(state)}
+	for _, module := range state.AllModules {
+		htmlModule := htmlModule{Name: module.Name, Path: module.Path, IsLoaded: module.IsLoaded, IsRoot: 0 == len(parents[module.Name])}
+		for _, instance := range module.ModuleInstances {
+			htmlModule.Instances = append(htmlModule.Instances, htmlInstance{Name: instance.InstanceName, Module: instance.ModulePath})
+		}
+		page.Modules = append(page.Modules, htmlModule)
+	}
+	sort.Slice(page.Modules, func(i, j int) bool { return page.Modules[i].Name < page.Modules[j].Name })
+
+	var buffer bytes.Buffer
+	err := htmlTemplate.Execute(&buffer, page)
+	if nil != err {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+var htmlTemplate = template.Must(template.New("hierarchy").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 0; display: flex; height: 100vh; }
+#tree { width: 40%; overflow: auto; border-right: 1px solid #ccc; padding: 8px; }
+#details { flex: 1; overflow: auto; padding: 8px; }
+#search { width: 100%; box-sizing: border-box; margin-bottom: 8px; }
+ul { list-style: none; padding-left: 16px; margin: 0; }
+.node { cursor: pointer; padding: 1px 4px; border-radius: 3px; }
+.node:hover { background: #eee; }
+.module > .node { font-weight: bold; }
+.kind { color: #888; font-size: 80%; margin-right: 4px; }
+.selected { background: #ffd54f !important; }
+.upstream { background: #bbdefb; }
+.downstream { background: #c8e6c9; }
+.hidden { display: none; }
+.legend span { padding: 1px 4px; margin-right: 8px; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ddd; padding: 2px 6px; text-align: left; }
+</style>
+</head>
+<body>
+<div id="tree">
+<input id="search" type="search" placeholder="search modules, inputs, outputs, resources">
+<div class="legend"><span class="selected">selected</span><span class="upstream">upstream</span><span class="downstream">downstream</span></div>
+<div id="root"></div>
+</div>
+<div id="details">Select a node to see where its value comes from and where it goes.</div>
+<script>
+var modules = {{.Modules}};
+var graph = {{.Graph}};
+
+var nodes = {}, outgoing = {}, incoming = {}, elements = {};
+(graph.Nodes || []).forEach(function (n) { nodes[n.ID] = n; });
+(graph.Edges || []).forEach(function (e) {
+  (outgoing[e.From] = outgoing[e.From] || []).push(e);
+  (incoming[e.To] = incoming[e.To] || []).push(e);
+});
+var modulesByName = {};
+(modules || []).forEach(function (m) { modulesByName[m.Name] = m; });
+
+function walk(id, index, next) {
+  var visited = {}, queue = [id], edges = [];
+  visited[id] = true;
+  while (queue.length) {
+    var current = queue.shift();
+    (index[current] || []).forEach(function (e) {
+      edges.push(e);
+      var n = next(e);
+      if (!visited[n]) { visited[n] = true; queue.push(n); }
+    });
+  }
+  return edges;
+}
+
+function text(tag, value, cls) {
+  var el = document.createElement(tag);
+  el.textContent = value;
+  if (cls) { el.className = cls; }
+  return el;
+}
+
+function nodeItem(id) {
+  var n = nodes[id];
+  var li = document.createElement("li");
+  var span = document.createElement("span");
+  span.className = "node";
+  span.appendChild(text("span", n.Kind, "kind"));
+  span.appendChild(document.createTextNode(n.Name));
+  span.onclick = function (ev) { ev.stopPropagation(); select(id); };
+  (elements[id] = elements[id] || []).push(span);
+  li.appendChild(span);
+  li.dataset.search = (n.Kind + " " + n.Module + " " + n.Name).toLowerCase();
+  return li;
+}
+
+function moduleItem(name, label, path) {
+  var li = document.createElement("li");
+  li.className = "module";
+  var id = "module:" + name;
+  var span = document.createElement("span");
+  span.className = "node";
+  span.appendChild(text("span", "module", "kind"));
+  span.appendChild(document.createTextNode(label));
+  (elements[id] = elements[id] || []).push(span);
+  li.appendChild(span);
+  li.dataset.search = ("module " + name + " " + label).toLowerCase();
+
+  var ul = document.createElement("ul");
+  ul.className = "hidden";
+  span.onclick = function (ev) { ev.stopPropagation(); ul.classList.toggle("hidden"); select(id); };
+  ["input", "local", "output", "resource"].forEach(function (kind) {
+    (graph.Nodes || []).forEach(function (n) {
+      if (n.Module === name && n.Kind === kind) { ul.appendChild(nodeItem(n.ID)); }
+    });
+  });
+  var m = modulesByName[name];
+  if (m && path.indexOf(name) < 0) {
+    (m.Instances || []).forEach(function (i) {
+      ul.appendChild(moduleItem(i.Module, "module." + i.Name + " (" + i.Module + ")", path.concat([name])));
+    });
+  }
+  li.appendChild(ul);
+  return li;
+}
+
+function clearHighlight() {
+  Object.keys(elements).forEach(function (id) {
+    elements[id].forEach(function (el) { el.classList.remove("selected", "upstream", "downstream"); });
+  });
+}
+
+function highlight(id, cls) {
+  (elements[id] || []).forEach(function (el) {
+    el.classList.add(cls);
+    for (var p = el.parentNode; p && p.id !== "root"; p = p.parentNode) {
+      if (p.tagName === "UL") { p.classList.remove("hidden"); }
+    }
+  });
+}
+
+function edgeTable(title, edges) {
+  var div = document.createElement("div");
+  div.appendChild(text("h3", title + " (" + edges.length + ")"));
+  if (!edges.length) { return div; }
+  var table = document.createElement("table");
+  var header = document.createElement("tr");
+  ["from", "edge", "to"].forEach(function (h) { header.appendChild(text("th", h)); });
+  table.appendChild(header);
+  edges.forEach(function (e) {
+    var tr = document.createElement("tr");
+    [e.From, e.Kind + " " + e.Label, e.To].forEach(function (v, i) {
+      var td = text("td", i === 1 ? v : nodes[v] ? nodes[v].Module + ": " + nodes[v].Name : v);
+      if (i !== 1) { td.className = "node"; td.onclick = function () { select(v); }; }
+      tr.appendChild(td);
+    });
+    table.appendChild(tr);
+  });
+  div.appendChild(table);
+  return div;
+}
+
+function select(id) {
+  clearHighlight();
+  var up = walk(id, incoming, function (e) { return e.From; });
+  var down = walk(id, outgoing, function (e) { return e.To; });
+  up.forEach(function (e) { highlight(e.From, "upstream"); });
+  down.forEach(function (e) { highlight(e.To, "downstream"); });
+  highlight(id, "selected");
+
+  var n = nodes[id];
+  var details = document.getElementById("details");
+  details.innerHTML = "";
+  details.appendChild(text("h2", n.Kind + " " + n.Name));
+  details.appendChild(text("div", "module: " + n.Module));
+  if (n.Pos && n.Pos.Filename) {
+    details.appendChild(text("div", "defined at: " + n.Pos.Filename + ":" + n.Pos.Line));
+  }
+  details.appendChild(edgeTable("upstream", up));
+  details.appendChild(edgeTable("downstream", down));
+}
+
+function filter(query) {
+  query = query.toLowerCase();
+  document.querySelectorAll("#root li").forEach(function (li) {
+    li.classList.toggle("hidden", query !== "" && li.textContent.toLowerCase().indexOf(query) < 0 && li.dataset.search.indexOf(query) < 0);
+  });
+  if (query !== "") {
+    document.querySelectorAll("#root ul").forEach(function (ul) { ul.classList.remove("hidden"); });
+  }
+}
+
+var root = document.createElement("ul");
+root.style.paddingLeft = "0";
+(modules || []).forEach(function (m) {
+  if (m.IsRoot) { root.appendChild(moduleItem(m.Name, m.Name, [])); }
+});
+document.getElementById("root").appendChild(root);
+document.getElementById("search").oninput = function (ev) { filter(ev.target.value); };
+</script>
+</body>
+</html>
+`))
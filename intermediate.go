@@ -1,6 +1,10 @@
 package main
 
 import (
+	"path/filepath"
+	"strings"
+	"sync"
+
 	log "github.com/Sirupsen/logrus"
 )
 
@@ -15,8 +19,25 @@ type ModuleInputUsage struct {
 	UsagePath [][]string      `form:"UsagePath" json:"UsagePath" xml:"UsagePath"`
 }
 
+// source code position of a declaration
+type SourcePos struct {
+	Filename string `form:"Filename" json:"Filename" xml:"Filename"`
+	Line     int    `form:"Line" json:"Line" xml:"Line"`
+	EndLine  int    `form:"EndLine" json:"EndLine" xml:"EndLine"`
+}
+
+func (p SourcePos) Contains(line int) bool {
+	return p.Line <= line && line <= p.EndLine
+}
+
 type ModuleInput struct {
 	Name          string                  `form:"Name" json:"Name" xml:"Name"`
+	Pos           SourcePos               `form:"Pos" json:"Pos" xml:"Pos"`
+	Required      bool                    `form:"Required" json:"Required" xml:"Required"`
+	Type          string                  `form:"Type" json:"Type" xml:"Type"`
+	Default       string                  `form:"Default" json:"Default" xml:"Default"`
+	Description   string                  `form:"Description" json:"Description" xml:"Description"`
+	Sensitive     bool                    `form:"Sensitive" json:"Sensitive,omitempty" xml:"Sensitive"`
 	IsLoaded      bool                    `form:"-" json:"-" xml:"-"`
 	AsArgument    []ResourceArgumentUsage `form:"AsArgument" json:"AsArgument" xml:"AsArgument"`
 	AsModuleInput []ModuleInputUsage      `form:"AsModuleInput" json:"AsModuleInput" xml:"AsModuleInput"`
@@ -24,51 +45,149 @@ type ModuleInput struct {
 
 // attributes/outputs
 type ResourceAttributeUsage struct {
-	Attr *ResourceAttribute `form:"Arg" json:"Arg" xml:"Arg"`
+	Attr      *ResourceAttribute `form:"Arg" json:"Arg" xml:"Arg"`
+	UsagePath [][]string         `form:"UsagePath" json:"UsagePath" xml:"UsagePath"`
 }
 
 type ModuleOutputUsage struct {
-	Input *ModuleInstance `form:"Input" json:"Input" xml:"Input"`
+	Input     *ModuleInstance `form:"Input" json:"Input" xml:"Input"`
+	UsagePath [][]string      `form:"UsagePath" json:"UsagePath" xml:"UsagePath"`
 }
 
 type ModuleOutput struct {
 	Name             string                   `form:"Name" json:"Name" xml:"Name"`
+	Pos              SourcePos                `form:"Pos" json:"Pos" xml:"Pos"`
+	Description      string                   `form:"Description" json:"Description" xml:"Description"`
+	Sensitive        bool                     `form:"Sensitive" json:"Sensitive,omitempty" xml:"Sensitive"`
 	IsLoaded         bool                     `form:"-" json:"-" xml:"-"`
 	FromAttribute    []ResourceAttributeUsage `form:"FromAttribute" json:"FromAttribute" xml:"FromAttribute"`
 	FromModuleOutput []ModuleOutputUsage      `form:"FromModuleOutput" json:"FromModuleOutput" xml:"FromModuleOutput"`
+	// names of the module inputs the value is built from
+	FromInput []string `form:"FromInput" json:"FromInput,omitempty" xml:"FromInput"`
 }
 
 // modules
 type ModuleInstance struct {
-	InstanceName string  `form:"InstanceName" json:"InstanceName" xml:"InstanceName"`
-	ModulePath   string  `form:"ModulePath" json:"ModulePath" xml:"ModulePath"`
-	Instance     *Module `form:"-" json:"-" xml:"-"`
+	InstanceName string    `form:"InstanceName" json:"InstanceName" xml:"InstanceName"`
+	ModulePath   string    `form:"ModulePath" json:"ModulePath" xml:"ModulePath"`
+	Source       string    `form:"Source" json:"Source,omitempty" xml:"Source"`
+	Pos          SourcePos `form:"Pos" json:"Pos" xml:"Pos"`
+	Instance     *Module   `form:"-" json:"-" xml:"-"`
+	// provider address in the called module -> provider address in the calling one
+	Providers map[string]string `form:"Providers" json:"Providers,omitempty" xml:"Providers"`
+}
+
+// managed resources
+type ModuleResource struct {
+	Type string    `form:"Type" json:"Type" xml:"Type"`
+	Name string    `form:"Name" json:"Name" xml:"Name"`
+	Pos  SourcePos `form:"Pos" json:"Pos" xml:"Pos"`
+	// provider meta-argument, empty when the default provider of the resource type is used
+	Provider string `form:"Provider" json:"Provider,omitempty" xml:"Provider"`
+}
+
+// provider configurations
+type ModuleProvider struct {
+	Name      string            `form:"Name" json:"Name" xml:"Name"`
+	Alias     string            `form:"Alias" json:"Alias,omitempty" xml:"Alias"`
+	Pos       SourcePos         `form:"Pos" json:"Pos" xml:"Pos"`
+	Arguments map[string]string `form:"Arguments" json:"Arguments" xml:"Arguments"`
+}
+
+// data "terraform_remote_state" sources and where their outputs are used
+type RemoteStateUsage struct {
+	Output string `form:"Output" json:"Output" xml:"Output"`
+	// argument, module-input or output
+	Kind      string   `form:"Kind" json:"Kind" xml:"Kind"`
+	UsagePath []string `form:"UsagePath" json:"UsagePath" xml:"UsagePath"`
+}
+
+type ModuleRemoteState struct {
+	Name     string             `form:"Name" json:"Name" xml:"Name"`
+	Pos      SourcePos          `form:"Pos" json:"Pos" xml:"Pos"`
+	IsLoaded bool               `form:"-" json:"-" xml:"-"`
+	Backend  string             `form:"Backend" json:"Backend" xml:"Backend"`
+	Config   map[string]string  `form:"Config" json:"Config" xml:"Config"`
+	Usages   []RemoteStateUsage `form:"Usages" json:"Usages" xml:"Usages"`
+}
+
+// locals, the value is kept as text and references in it are resolved by the flow graph
+type LocalUsage struct {
+	// argument, module-input, output or local
+	Kind      string   `form:"Kind" json:"Kind" xml:"Kind"`
+	UsagePath []string `form:"UsagePath" json:"UsagePath" xml:"UsagePath"`
+}
+
+type ModuleLocal struct {
+	Name     string       `form:"Name" json:"Name" xml:"Name"`
+	Pos      SourcePos    `form:"Pos" json:"Pos" xml:"Pos"`
+	IsLoaded bool         `form:"-" json:"-" xml:"-"`
+	Value    string       `form:"Value" json:"Value" xml:"Value"`
+	Usages   []LocalUsage `form:"Usages" json:"Usages" xml:"Usages"`
+}
+
+// terraform block settings
+type ProviderRequirement struct {
+	Source  string `form:"Source" json:"Source,omitempty" xml:"Source"`
+	Version string `form:"Version" json:"Version" xml:"Version"`
+}
+
+type ModuleSettings struct {
+	Pos               SourcePos                      `form:"Pos" json:"Pos" xml:"Pos"`
+	RequiredVersion   string                         `form:"RequiredVersion" json:"RequiredVersion,omitempty" xml:"RequiredVersion"`
+	RequiredProviders map[string]ProviderRequirement `form:"RequiredProviders" json:"RequiredProviders,omitempty" xml:"RequiredProviders"`
+	Backend           string                         `form:"Backend" json:"Backend,omitempty" xml:"Backend"`
+	BackendConfig     map[string]string              `form:"BackendConfig" json:"BackendConfig,omitempty" xml:"BackendConfig"`
+}
+
+// name.alias as used by provider meta-arguments
+func (p ModuleProvider) Address() string {
+	if "" == p.Alias {
+		return p.Name
+	}
+	return p.Name + "." + p.Alias
 }
 
 type Module struct {
-	Name            string           `form:"Name" json:"Name" xml:"Name"`
-	IsLoaded        bool             `form:"-" json:"-" xml:"-"`
-	ModuleInstances []ModuleInstance `form:"ModuleInstances" json:"ModuleInstances" xml:"ModuleInstances"`
-	Inputs          []*ModuleInput   `form:"Inputs" json:"Inputs" xml:"Inputs"`
-	Outputs         []*ModuleOutput  `form:"Outputs" json:"Outputs" xml:"Outputs"`
+	Name            string               `form:"Name" json:"Name" xml:"Name"`
+	Path            string               `form:"Path" json:"Path" xml:"Path"`
+	IsLoaded        bool                 `form:"-" json:"-" xml:"-"`
+	ModuleInstances []*ModuleInstance    `form:"ModuleInstances" json:"ModuleInstances" xml:"ModuleInstances"`
+	Inputs          []*ModuleInput       `form:"Inputs" json:"Inputs" xml:"Inputs"`
+	Outputs         []*ModuleOutput      `form:"Outputs" json:"Outputs" xml:"Outputs"`
+	Resources       []ModuleResource     `form:"Resources" json:"Resources" xml:"Resources"`
+	Providers       []ModuleProvider     `form:"Providers" json:"Providers,omitempty" xml:"Providers"`
+	Terraform       *ModuleSettings      `form:"Terraform" json:"Terraform,omitempty" xml:"Terraform"`
+	RemoteStates    []*ModuleRemoteState `form:"RemoteStates" json:"RemoteStates,omitempty" xml:"RemoteStates"`
+	Terragrunt      *TerragruntConfig    `form:"Terragrunt" json:"Terragrunt,omitempty" xml:"Terragrunt"`
+	Locals          []*ModuleLocal       `form:"Locals" json:"Locals,omitempty" xml:"Locals"`
+	// files of the module failing to load
+	LoadErrors []ModuleLoadError `form:"LoadErrors" json:"LoadErrors,omitempty" xml:"LoadErrors"`
+}
+
+type ModuleLoadError struct {
+	Pos     SourcePos `form:"Pos" json:"Pos" xml:"Pos"`
+	Message string    `form:"Message" json:"Message" xml:"Message"`
 }
 
 // The state
+// every module, input, output and module instance is allocated separately and is identified by its name
+// (see variableKey), so pointers handed out stay valid however large the hierarchy grows
 type HierarchyState struct {
-	AllModules []Module `form:"AllModules" json:"AllModules" xml:"AllModules"`
-	allInputs  []ModuleInput
-	allOutputs []ModuleOutput
+	AllModules []*Module `form:"AllModules" json:"AllModules" xml:"AllModules"`
 
 	allModulesMap map[string]*Module
 	allInputsMap  map[string]*ModuleInput
 	allOutputsMap map[string]*ModuleOutput
+
+	// guards modules and maps above, methods of HierarchyState are safe for concurrent use,
+	// methods of a single Module are not
+	mutex sync.Mutex
 }
 
 func NewHierarchyState() *HierarchyState {
 	return &HierarchyState{
-		AllModules:    make([]Module, 0, 128),
-		allInputs:     make([]ModuleInput, 0, 2048),
-		allOutputs:    make([]ModuleOutput, 0, 2048),
+		AllModules:    make([]*Module, 0, 128),
 		allModulesMap: make(map[string]*Module),
 		allInputsMap:  make(map[string]*ModuleInput),
 		allOutputsMap: make(map[string]*ModuleOutput),
@@ -91,51 +210,162 @@ type ModuleFieldID struct {
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 // Module methods
 func (h *HierarchyState) NewModule(name string) *Module {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
 	m, found := h.allModulesMap[name]
 	if !found {
-		h.AllModules = append(h.AllModules,
-			Module{
-				Name:            name,
-				IsLoaded:        false,
-				ModuleInstances: make([]ModuleInstance, 0, 128),
-				Inputs:          make([]*ModuleInput, 0, 128),
-				Outputs:         make([]*ModuleOutput, 0, 128),
-			})
-		m = &h.AllModules[len(h.AllModules)-1]
+		m = &Module{
+			Name:            name,
+			IsLoaded:        false,
+			ModuleInstances: make([]*ModuleInstance, 0, 128),
+			Inputs:          make([]*ModuleInput, 0, 128),
+			Outputs:         make([]*ModuleOutput, 0, 128),
+		}
+		h.AllModules = append(h.AllModules, m)
 		h.allModulesMap[name] = m
 	}
 	return m
 }
 
+// forgets everything loaded from module files, the module itself and references to it stay valid
+func (h *HierarchyState) ResetModule(module *Module) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, input := range module.Inputs {
+		delete(h.allInputsMap, variableKey(module, VariableID(input.Name)))
+	}
+	for _, output := range module.Outputs {
+		delete(h.allOutputsMap, variableKey(module, VariableID(output.Name)))
+	}
+
+	module.IsLoaded = false
+	module.ModuleInstances = make([]*ModuleInstance, 0, 128)
+	module.Inputs = make([]*ModuleInput, 0, 128)
+	module.Outputs = make([]*ModuleOutput, 0, 128)
+	module.Resources = nil
+	module.Providers = nil
+	module.Terraform = nil
+	module.RemoteStates = nil
+	module.Terragrunt = nil
+	module.Locals = nil
+	module.LoadErrors = nil
+}
+
 func (m *Module) FindModuleInstance(instanceName string) *ModuleInstance {
 	for _, instance := range m.ModuleInstances {
 		if instance.InstanceName == instanceName {
-			return &instance
+			return instance
 		}
 	}
 	return nil
 }
 
-func (m *Module) NewInstance(instanceName string, instanceSubmodulePath string, instance *Module) {
+func (m *Module) NewInstance(instanceName string, instanceSubmodulePath string, instance *Module, pos SourcePos) {
 	if nil == m.FindModuleInstance(instanceName) {
-		m.ModuleInstances = append(m.ModuleInstances, ModuleInstance{Instance: instance, ModulePath: instance.Name, InstanceName: instanceName})
+		m.ModuleInstances = append(m.ModuleInstances, &ModuleInstance{Instance: instance, ModulePath: instance.Name, Source: instanceSubmodulePath, InstanceName: instanceName, Pos: pos})
+	}
+}
+
+func (m *Module) NewResource(resourceType string, name string, provider string, pos SourcePos) {
+	for _, resource := range m.Resources {
+		if resource.Type == resourceType && resource.Name == name {
+			return
+		}
+	}
+	m.Resources = append(m.Resources, ModuleResource{Type: resourceType, Name: name, Pos: pos, Provider: provider})
+}
+
+func (m *Module) NewLoadError(pos SourcePos, message string) {
+	m.LoadErrors = append(m.LoadErrors, ModuleLoadError{Pos: pos, Message: message})
+}
+
+func (m *Module) NewProvider(provider ModuleProvider) {
+	for _, existing := range m.Providers {
+		if existing.Address() == provider.Address() {
+			log.Warningf("module %s: duplicate provider configuration %s", m.Name, provider.Address())
+			return
+		}
+	}
+	m.Providers = append(m.Providers, provider)
+}
+
+// remote states may be used before they are declared
+func (m *Module) NewRemoteState(name string) *ModuleRemoteState {
+	for _, remoteState := range m.RemoteStates {
+		if remoteState.Name == name {
+			return remoteState
+		}
+	}
+	remoteState := &ModuleRemoteState{Name: name, Config: make(map[string]string)}
+	m.RemoteStates = append(m.RemoteStates, remoteState)
+	return remoteState
+}
+
+// locals may be used before they are declared
+func (m *Module) NewLocal(name string) *ModuleLocal {
+	for _, local := range m.Locals {
+		if local.Name == name {
+			return local
+		}
+	}
+	local := &ModuleLocal{Name: name}
+	m.Locals = append(m.Locals, local)
+	return local
+}
+
+func (m *ModuleLocal) AttachUsage(kind string, usagePath []string) {
+	for _, usage := range m.Usages {
+		if usage.Kind == kind && strings.Join(usage.UsagePath, ".") == strings.Join(usagePath, ".") {
+			return
+		}
+	}
+	m.Usages = append(m.Usages, LocalUsage{Kind: kind, UsagePath: append([]string{}, usagePath...)})
+}
+
+func (m *ModuleRemoteState) AttachUsage(output string, kind string, usagePath []string) {
+	for _, usage := range m.Usages {
+		if usage.Output == output && usage.Kind == kind && strings.Join(usage.UsagePath, ".") == strings.Join(usagePath, ".") {
+			return
+		}
 	}
+	m.Usages = append(m.Usages, RemoteStateUsage{Output: output, Kind: kind, UsagePath: append([]string{}, usagePath...)})
+}
+
+func (m *Module) FindProvider(address string) (ModuleProvider, bool) {
+	for _, provider := range m.Providers {
+		if provider.Address() == address {
+			return provider, true
+		}
+	}
+	return ModuleProvider{}, false
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 // Module inputs
-func (h *HierarchyState) NewInput(module *Module, id VariableID) *ModuleInput {
-	name := string(id)
-	inputKey := module.Name + "." + name
+func variableKey(module *Module, id VariableID) string {
 	if "." == module.Name {
 		// root module
-		inputKey = "." + name
+		return "." + string(id)
 	}
+	return module.Name + "." + string(id)
+}
+
+func (h *HierarchyState) NewInput(module *Module, id VariableID) *ModuleInput {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.newInput(module, id)
+}
+
+func (h *HierarchyState) newInput(module *Module, id VariableID) *ModuleInput {
+	name := string(id)
+	inputKey := variableKey(module, id)
 
 	input, found := h.allInputsMap[inputKey]
 	if !found {
-		h.allInputs = append(h.allInputs, ModuleInput{Name: name, IsLoaded: false})
-		input = &h.allInputs[len(h.allInputs)-1]
+		input = &ModuleInput{Name: name, IsLoaded: false}
 		h.allInputsMap[inputKey] = input
 		module.Inputs = append(module.Inputs, input)
 	}
@@ -143,8 +373,9 @@ func (h *HierarchyState) NewInput(module *Module, id VariableID) *ModuleInput {
 }
 
 func (m *ModuleInput) AttachArgument(usagePath []string, argument *ResourceArgument) {
-	for _, elem := range m.AsArgument {
+	for i, elem := range m.AsArgument {
 		if elem.Arg == argument {
+			m.AsArgument[i].UsagePath = appendUsagePath(elem.UsagePath, usagePath)
 			return
 		}
 	}
@@ -153,8 +384,9 @@ func (m *ModuleInput) AttachArgument(usagePath []string, argument *ResourceArgum
 }
 
 func (m *ModuleInput) AttachModuleInput(usagePath []string, instance *ModuleInstance) {
-	for _, elem := range m.AsModuleInput {
+	for i, elem := range m.AsModuleInput {
 		if elem.Input == instance {
+			m.AsModuleInput[i].UsagePath = appendUsagePath(elem.UsagePath, usagePath)
 			return
 		}
 	}
@@ -162,15 +394,30 @@ func (m *ModuleInput) AttachModuleInput(usagePath []string, instance *ModuleInst
 	m.AsModuleInput = append(m.AsModuleInput, ModuleInputUsage{Input: instance, UsagePath: [][]string{usagePath}})
 }
 
+func appendUsagePath(usagePaths [][]string, usagePath []string) [][]string {
+	for _, path := range usagePaths {
+		if strings.Join(path, ".") == strings.Join(usagePath, ".") {
+			return usagePaths
+		}
+	}
+	return append(usagePaths, usagePath)
+}
+
 func (h *HierarchyState) ConnectInputToArgument(module *Module, id VariableID, usagePath []string, argument *ResourceArgument) {
 	log.Debugf("module %v name %v attach argument %v", module.Name, id, argument)
-	value := h.NewInput(module, id)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	value := h.newInput(module, id)
 	value.AttachArgument(usagePath, argument)
 }
 
 func (h *HierarchyState) ConnectInputToModuleInput(module *Module, id VariableID, usagePath []string, instance *ModuleInstance) {
 	log.Debugf("module %v name %v attach input %v", module.Name, id, instance)
-	value := h.NewInput(module, id)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	value := h.newInput(module, id)
 	value.AttachModuleInput(usagePath, instance)
 }
 
@@ -178,51 +425,125 @@ func (h *HierarchyState) ConnectInputToModuleInput(module *Module, id VariableID
 // Module outputs
 
 func (h *HierarchyState) NewOutput(module *Module, id VariableID) *ModuleOutput {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.newOutput(module, id)
+}
+
+func (h *HierarchyState) newOutput(module *Module, id VariableID) *ModuleOutput {
 	name := string(id)
-	outputKey := module.Name + "." + name
-	if "." == module.Name {
-		// root module
-		outputKey = "." + name
-	}
+	outputKey := variableKey(module, id)
 
 	output, found := h.allOutputsMap[outputKey]
 	if !found {
-		h.allOutputs = append(h.allOutputs, ModuleOutput{Name: name, IsLoaded: false})
-		output = &h.allOutputs[len(h.allOutputs)-1]
+		output = &ModuleOutput{Name: name, IsLoaded: false}
 		h.allOutputsMap[outputKey] = output
 		module.Outputs = append(module.Outputs, output)
 	}
 	return output
 }
 
-func (m *ModuleOutput) AttachAttribute(attribute *ResourceAttribute) {
-	for _, elem := range m.FromAttribute {
+func (m *ModuleOutput) AttachAttribute(usagePath []string, attribute *ResourceAttribute) {
+	for i, elem := range m.FromAttribute {
 		if elem.Attr == attribute {
+			m.FromAttribute[i].UsagePath = appendUsagePath(elem.UsagePath, usagePath)
 			return
 		}
 	}
 
-	m.FromAttribute = append(m.FromAttribute, ResourceAttributeUsage{Attr: attribute})
+	m.FromAttribute = append(m.FromAttribute, ResourceAttributeUsage{Attr: attribute, UsagePath: [][]string{usagePath}})
 }
 
-func (m *ModuleOutput) AttachModuleOutput(instance *ModuleInstance) {
-	for _, elem := range m.FromModuleOutput {
+func (m *ModuleOutput) AttachModuleOutput(usagePath []string, instance *ModuleInstance) {
+	for i, elem := range m.FromModuleOutput {
 		if elem.Input == instance {
+			m.FromModuleOutput[i].UsagePath = appendUsagePath(elem.UsagePath, usagePath)
 			return
 		}
 	}
 
-	m.FromModuleOutput = append(m.FromModuleOutput, ModuleOutputUsage{Input: instance})
+	m.FromModuleOutput = append(m.FromModuleOutput, ModuleOutputUsage{Input: instance, UsagePath: [][]string{usagePath}})
+}
+
+func (m *ModuleOutput) AttachInput(name string) {
+	if !Include(m.FromInput, name) {
+		m.FromInput = append(m.FromInput, name)
+	}
 }
 
-func (h *HierarchyState) ConnectOutputToAttribute(module *Module, id VariableID, attribute *ResourceAttribute) {
+// inputs used by outputs are created as well, so that undeclared ones are known
+func (h *HierarchyState) ConnectOutputToInput(module *Module, id VariableID, input VariableID) {
+	log.Debugf("module %v name %v attach input %v", module.Name, id, input)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.newInput(module, input)
+	value := h.newOutput(module, id)
+	value.AttachInput(string(input))
+}
+
+func (h *HierarchyState) ConnectOutputToAttribute(module *Module, id VariableID, resourceField ResourceFieldID, attribute *ResourceAttribute) {
 	log.Debugf("module %v name %v attach attribute %v", module.Name, id, attribute)
-	value := h.NewOutput(module, id)
-	value.AttachAttribute(attribute)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	value := h.newOutput(module, id)
+	value.AttachAttribute([]string{resourceField.Name, resourceField.InstanceName, resourceField.FieldName}, attribute)
+}
+
+func (h *HierarchyState) ConnectOutputToModuleOutput(module *Module, instance *ModuleInstance, id VariableID, moduleFieldUsage ModuleFieldID) {
+	log.Debugf("instance %v name %v attach module output %v", instance, id, moduleFieldUsage)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	value := h.newOutput(module, id)
+	value.AttachModuleOutput([]string{moduleFieldUsage.InstanceName, moduleFieldUsage.FieldName}, instance)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Module hierarchy
+
+// loaded module located in the absolute directory, module paths are relative to root
+func findDirModule(state *HierarchyState, root string, dir string) *Module {
+	for _, module := range state.AllModules {
+		if !module.IsLoaded {
+			continue
+		}
+		moduleDir, err := filepath.Abs(filepath.Join(root, module.Path))
+		if nil == err && moduleDir == dir {
+			return module
+		}
+	}
+	return nil
+}
+
+// module name -> names of modules calling it
+func moduleParents(state *HierarchyState) map[string][]string {
+	parents := make(map[string][]string)
+	for _, module := range state.AllModules {
+		for _, instance := range module.ModuleInstances {
+			if !Include(parents[instance.ModulePath], module.Name) {
+				parents[instance.ModulePath] = append(parents[instance.ModulePath], module.Name)
+			}
+		}
+	}
+	return parents
+}
+
+// loaded modules nobody calls, directories without configuration are left out
+func rootModules(state *HierarchyState) []*Module {
+	var result []*Module
+	parents := moduleParents(state)
+	for _, module := range state.AllModules {
+		if module.IsLoaded && 0 == len(parents[module.Name]) && !isEmptyModule(module) {
+			result = append(result, module)
+		}
+	}
+	return result
 }
 
-func (h *HierarchyState) ConnectOutputToModuleOutput(instance *ModuleInstance, id VariableID, moduleFieldUsage ModuleFieldID) {
-	log.Debugf("instance %v name %v attach module output %v", instance, moduleFieldUsage)
-	value := h.NewOutput(instance.Instance, id)
-	value.AttachModuleOutput(instance)
+func isEmptyModule(module *Module) bool {
+	return 0 == len(module.Inputs) && 0 == len(module.Outputs) && 0 == len(module.Resources) &&
+		0 == len(module.ModuleInstances) && 0 == len(module.Providers) && nil == module.Terraform && 0 == len(module.RemoteStates) && nil == module.Terragrunt && 0 == len(module.Locals)
 }
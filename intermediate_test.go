@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHierarchyStateHandles(t *testing.T) {
+	Convey("Handles must stay valid in large hierarchies", t, func() {
+		state := NewHierarchyState()
+		root := state.NewModule(".")
+		first := state.NewInput(root, "first")
+
+		for i := 0; i < 300; i++ {
+			module := state.NewModule(fmt.Sprintf("module_%d", i))
+			for j := 0; j < 10; j++ {
+				state.NewInput(module, VariableID(fmt.Sprintf("input_%d", j)))
+				state.NewOutput(module, VariableID(fmt.Sprintf("output_%d", j)))
+			}
+			root.NewInstance(fmt.Sprintf("instance_%d", i), "", module, SourcePos{})
+		}
+
+		state.ConnectInputToModuleInput(root, "first", []string{"instance_0", "input_0"}, root.FindModuleInstance("instance_0"))
+		So(state.allModulesMap["."], ShouldEqual, root)
+		So(state.AllModules[0], ShouldEqual, root)
+		So(state.NewInput(root, "first"), ShouldEqual, first)
+		So(len(first.AsModuleInput), ShouldEqual, 1)
+		So(first.AsModuleInput[0].Input, ShouldEqual, root.FindModuleInstance("instance_0"))
+
+		instance := root.FindModuleInstance("instance_299")
+		So(instance, ShouldNotBeNil)
+		So(instance.Instance, ShouldEqual, state.allModulesMap["module_299"])
+		So(len(instance.Instance.Inputs), ShouldEqual, 10)
+	})
+	Convey("Usages of one resource argument must be merged", t, func() {
+		awsResources := []Resource{{
+			Name:       "aws_instance",
+			Arguments:  []ResourceArgument{{Name: "ami"}, {Name: "user_data"}},
+			Attributes: []ResourceAttribute{{Name: "id"}},
+		}}
+		argument := getArgumentByName("aws_instance", "user_data", awsResources)
+		So(argument, ShouldEqual, &awsResources[0].Arguments[1])
+		So(getAttributeByName("\"aws_instance\"", "id", awsResources), ShouldEqual, &awsResources[0].Attributes[0])
+		So(getArgumentByName("aws_instance", "missing", awsResources), ShouldBeNil)
+
+		input := &ModuleInput{Name: "script"}
+		input.AttachArgument([]string{"aws_instance", "web", "user_data"}, argument)
+		input.AttachArgument([]string{"aws_instance", "worker", "user_data"}, getArgumentByName("aws_instance", "user_data", awsResources))
+		So(len(input.AsArgument), ShouldEqual, 1)
+		So(input.AsArgument[0].UsagePath, ShouldResemble, [][]string{{"aws_instance", "web", "user_data"}, {"aws_instance", "worker", "user_data"}})
+	})
+}
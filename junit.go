@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// lint results as JUnit XML: a test suite per module, a test case per rule
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	// findings of info severity do not fail the case
+	SystemOut string `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// conversion
+func WriteJUnit(state *HierarchyState, findings []LintFinding, rules []LintRule, config *lintConfig) ([]byte, error) {
+	byModule := make(map[string][]LintFinding)
+	var modules []string
+	for _, module := range state.AllModules {
+		// directories holding only other modules are not suites
+		if module.IsLoaded && !isEmptyModule(module) {
+			modules = append(modules, module.Name)
+		}
+	}
+	for _, finding := range findings {
+		byModule[finding.Module] = append(byModule[finding.Module], finding)
+		modules = append(modules, finding.Module)
+	}
+	modules = sortedUnique(modules)
+
+	report := junitTestSuites{Name: "terraform-hierarchy"}
+	for _, module := range modules {
+		suite := junitTestSuite{Name: module}
+		for _, rule := range rules {
+			testCase := junitTestCase{Name: rule.ID, ClassName: module}
+			severity := config.Severity(rule)
+			if severityOff == severity {
+				testCase.Skipped = &junitSkipped{Message: "rule is disabled"}
+				suite.Skipped++
+			} else {
+				var failed, noted []string
+				for _, finding := range byModule[module] {
+					if finding.Rule != rule.ID {
+						continue
+					}
+					if severityInfo == finding.Severity {
+						noted = append(noted, junitFindingText(finding))
+					} else {
+						failed = append(failed, junitFindingText(finding))
+					}
+				}
+				if 0 != len(failed) {
+					testCase.Failure = &junitFailure{Message: fmt.Sprintf("%s: %d findings", rule.Description, len(failed)), Type: severity, Text: strings.Join(failed, "\n")}
+					suite.Failures++
+				}
+				testCase.SystemOut = strings.Join(noted, "\n")
+			}
+			suite.Cases = append(suite.Cases, testCase)
+			suite.Tests++
+		}
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, suite)
+	}
+
+	body, err := xml.MarshalIndent(report, "", "  ")
+	if nil != err {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// file:line: message
+func junitFindingText(finding LintFinding) string {
+	if "" == finding.Pos.Filename {
+		return finding.Message
+	}
+	return fmt.Sprintf("%s:%d: %s", finding.Pos.Filename, finding.Pos.Line, finding.Message)
+}
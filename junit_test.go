@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/xml"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	Convey("Every module must be a test suite with a test case per rule", t, func() {
+		state := loadTestState("testdata/lint")
+		config, err := readLintConfig("testdata/lint/" + hierarchyLintFile)
+		So(err, ShouldBeNil)
+		config.Severities["sensitive-flow"] = severityOff
+		config.Severities["missing-description"] = severityInfo
+		findings := RunLint(state, nil, config)
+
+		report, err := WriteJUnit(state, findings, lintRules, config)
+		So(err, ShouldBeNil)
+
+		var suites junitTestSuites
+		So(xml.Unmarshal(report, &suites), ShouldBeNil)
+		var names []string
+		for _, suite := range suites.Suites {
+			names = append(names, suite.Name)
+		}
+		So(names, ShouldResemble, []string{".", "broken", "modules.app", "modules.usage"})
+		So(suites.Tests, ShouldEqual, 4*len(lintRules))
+
+		root := suites.Suites[0]
+		So(root.Failures, ShouldEqual, 3)
+		So(root.Skipped, ShouldEqual, 1)
+		for _, testCase := range root.Cases {
+			switch testCase.Name {
+			case "unused-variable":
+				So(testCase.Failure, ShouldNotBeNil)
+				So(testCase.Failure.Type, ShouldEqual, severityWarning)
+				So(testCase.Failure.Text, ShouldEqual, "testdata/lint/main.tf:10: variable unused is not used")
+			case "missing-description":
+				So(testCase.Failure, ShouldBeNil)
+				So(testCase.SystemOut, ShouldEqual, "testdata/lint/main.tf:24: output app_id has no description")
+			case "sensitive-flow":
+				So(testCase.Skipped, ShouldNotBeNil)
+			case "unsatisfiable-versions":
+				So(testCase.Failure, ShouldBeNil)
+			}
+		}
+
+		So(suites.Suites[2].Failures, ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// lint rules over the loaded hierarchy
+const hierarchyLintFile = ".hierarchylint"
+
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+	severityOff     = "off"
+)
+
+type LintFinding struct {
+	Rule     string    `form:"Rule" json:"Rule" xml:"Rule"`
+	Severity string    `form:"Severity" json:"Severity" xml:"Severity"`
+	Module   string    `form:"Module" json:"Module" xml:"Module"`
+	Message  string    `form:"Message" json:"Message" xml:"Message"`
+	Pos      SourcePos `form:"Pos" json:"Pos" xml:"Pos"`
+}
+
+type LintRule struct {
+	ID string `form:"ID" json:"ID" xml:"ID"`
+	// default severity, may be changed by the lint config
+	Severity    string                               `form:"Severity" json:"Severity" xml:"Severity"`
+	Description string                               `form:"Description" json:"Description" xml:"Description"`
+	Check       func(ctx *lintContext) []LintFinding `form:"-" json:"-" xml:"-"`
+}
+
+// rule severities and options, read from .hierarchylint of the terraform root:
+//
+//	unused-variable = off
+//	missing-description = warning
+//	<rule>.<option> = value
+type lintConfig struct {
+	Severities map[string]string
+	Options    map[string]string
+}
+
+type lintContext struct {
+	State     *HierarchyState
+	Resources []Resource
+	Config    *lintConfig
+	// file lines by path, read on demand
+	files map[string][]string
+}
+
+var lintRules = []LintRule{
+	{ID: "invalid-file", Severity: severityError, Description: "configuration file can not be parsed", Check: checkInvalidFiles},
+	{ID: "undeclared-variable", Severity: severityError, Description: "variable is used or passed to a module but not declared", Check: checkUndeclaredVariables},
+	{ID: "unused-variable", Severity: severityWarning, Description: "declared variable is never referenced in its module", Check: checkUnusedVariables},
+	{ID: "missing-module", Severity: severityError, Description: "module call with a local source that does not exist", Check: checkMissingModules},
+	{ID: "missing-description", Severity: severityInfo, Description: "variable or output without description", Check: checkMissingDescriptions},
+	{ID: "sensitive-flow", Severity: severityWarning, Description: "sensitive value reaches an output or argument shown in plain text", Check: checkSensitiveFlows},
+	{ID: namingConventionRule, Severity: severityWarning, Description: "variable, output, resource or module name does not match the configured pattern", Check: checkNamingConventions},
+	{ID: "module-input-name", Severity: severityInfo, Description: "variable passed as is to a module argument of another name", Check: checkModuleInputNames},
+	{ID: "unsatisfiable-versions", Severity: severityError, Description: "version constraints of a root and its modules can not be met together", Check: checkUnsatisfiableVersions},
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// command
+func runLint(args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	configPath := flags.String("config", "", "lint config, "+hierarchyLintFile+" of the terraform root by default")
+	var disabled globList
+	flags.Var(&disabled, "disable", "rule to skip, may be repeated or comma separated")
+	list := flags.Bool("list", false, "print available rules")
+	flags.Parse(args)
+
+	if *list {
+		jsonRules, err := json.Marshal(lintRules)
+		if nil != err {
+			return err
+		}
+		return writeOutput(jsonRules)
+	}
+
+	if "" == *configPath {
+		*configPath = filepath.Join(*rootDir, hierarchyLintFile)
+	}
+	config, err := readLintConfig(*configPath)
+	if nil != err {
+		return err
+	}
+	for _, rule := range disabled {
+		config.Severities[rule] = severityOff
+	}
+
+	state, awsResources, err := loadState()
+	if nil != err {
+		return err
+	}
+
+	findings := RunLint(state, awsResources, config)
+	var report []byte
+	switch *outFormat {
+	case "json":
+		report, err = json.Marshal(findings)
+	case "sarif":
+		report, err = WriteSARIF(findings, lintRules, config, *rootDir)
+	case "junit":
+		report, err = WriteJUnit(state, findings, lintRules, config)
+	default:
+		return fmt.Errorf("unknown output format '%s' of lint, expected json, sarif or junit", *outFormat)
+	}
+	if nil != err {
+		return err
+	}
+	err = writeOutput(report)
+	if nil != err {
+		return err
+	}
+	return lintError(findings)
+}
+
+// error findings fail the command, warnings and infos are only reported
+func lintError(findings []LintFinding) error {
+	errors := 0
+	for _, finding := range findings {
+		if severityError == finding.Severity {
+			errors++
+		}
+	}
+	if 0 != errors {
+		return fmt.Errorf("%d lint errors found", errors)
+	}
+	return nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// engine
+func newLintConfig() *lintConfig {
+	return &lintConfig{Severities: make(map[string]string), Options: make(map[string]string)}
+}
+
+func readLintConfig(filePath string) (*lintConfig, error) {
+	config := newLintConfig()
+	lines, err := readIgnoreFile(filePath)
+	if nil != err {
+		return nil, fmt.Errorf("lint config %s: %v", filePath, err)
+	}
+
+	for _, line := range lines {
+		parts := strings.SplitN(line, "=", 2)
+		if 2 != len(parts) {
+			return nil, fmt.Errorf("lint config %s: '<rule> = <severity>' or '<rule>.<option> = <value>' expected, got '%s'", filePath, line)
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+		if strings.Contains(key, ".") {
+			config.Options[key] = value
+			continue
+		}
+		if nil == findLintRule(key) {
+			log.Warningf("lint config %s: unknown rule '%s'", filePath, key)
+		}
+		switch value {
+		case severityError, severityWarning, severityInfo, severityOff:
+			config.Severities[key] = value
+		default:
+			return nil, fmt.Errorf("lint config %s: unknown severity '%s' of %s", filePath, value, key)
+		}
+	}
+	return config, nil
+}
+
+func findLintRule(id string) *LintRule {
+	for i := range lintRules {
+		if lintRules[i].ID == id {
+			return &lintRules[i]
+		}
+	}
+	return nil
+}
+
+func (c *lintConfig) Severity(rule LintRule) string {
+	if severity, found := c.Severities[rule.ID]; found {
+		return severity
+	}
+	return rule.Severity
+}
+
+func (c *lintConfig) Option(rule string, name string, defaultValue string) string {
+	if value, found := c.Options[rule+"."+name]; found {
+		return value
+	}
+	return defaultValue
+}
+
+func RunLint(state *HierarchyState, awsResources []Resource, config *lintConfig) []LintFinding {
+	ctx := &lintContext{State: state, Resources: awsResources, Config: config, files: make(map[string][]string)}
+
+	var findings []LintFinding
+	for _, rule := range lintRules {
+		severity := config.Severity(rule)
+		if severityOff == severity {
+			continue
+		}
+		for _, finding := range rule.Check(ctx) {
+			if ctx.IsIgnored(rule.ID, finding.Pos) {
+				continue
+			}
+			finding.Rule = rule.ID
+			finding.Severity = severity
+			findings = append(findings, finding)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Pos.Filename != b.Pos.Filename {
+			return a.Pos.Filename < b.Pos.Filename
+		}
+		if a.Pos.Line != b.Pos.Line {
+			return a.Pos.Line < b.Pos.Line
+		}
+		return a.Rule < b.Rule
+	})
+	return findings
+}
+
+var lintIgnoreRegexp = regexp.MustCompile("(?:#|//)\\s*hierarchy:ignore\\s+([-a-z0-9_, ]+)")
+
+// "# hierarchy:ignore rule[,rule]" on the line of the finding or the line above it
+func (ctx *lintContext) IsIgnored(rule string, pos SourcePos) bool {
+	lines := ctx.fileLines(pos.Filename)
+	for line := pos.Line - 1; line <= pos.Line; line++ {
+		if line < 1 || line > len(lines) {
+			continue
+		}
+		for _, match := range lintIgnoreRegexp.FindAllStringSubmatch(lines[line-1], -1) {
+			for _, ignored := range strings.FieldsFunc(match[1], func(c rune) bool { return ',' == c || ' ' == c }) {
+				if ignored == rule {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (ctx *lintContext) fileLines(filePath string) []string {
+	if "" == filePath {
+		return nil
+	}
+	lines, found := ctx.files[filePath]
+	if !found {
+		content, err := ioutil.ReadFile(filePath)
+		if nil == err {
+			lines = strings.Split(string(content), "\n")
+		}
+		ctx.files[filePath] = lines
+	}
+	return lines
+}
+
+// configuration files of a loaded module
+func (ctx *lintContext) moduleFiles(module *Module) []string {
+	files, err := ioutil.ReadDir(filepath.Join(*rootDir, module.Path))
+	if nil != err {
+		return nil
+	}
+	filter := newPathFilter(*rootDir)
+	var result []string
+	for _, file := range files {
+		if !file.IsDir() && isModuleFileName(file.Name()) && !filter.IsExcluded(filepath.Join(module.Path, file.Name())) {
+			result = append(result, filepath.Join(*rootDir, module.Path, file.Name()))
+		}
+	}
+	return result
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// rules
+// files failing to parse are skipped on load and recorded as load errors of their modules
+func checkInvalidFiles(ctx *lintContext) []LintFinding {
+	var findings []LintFinding
+	for _, module := range ctx.State.AllModules {
+		for _, loadError := range module.LoadErrors {
+			findings = append(findings, LintFinding{Module: module.Name, Message: loadError.Message, Pos: loadError.Pos})
+		}
+	}
+	return findings
+}
+
+func checkUndeclaredVariables(ctx *lintContext) []LintFinding {
+	var findings []LintFinding
+	for _, module := range ctx.State.AllModules {
+		if !module.IsLoaded {
+			continue
+		}
+		for _, input := range module.Inputs {
+			if input.IsLoaded {
+				continue
+			}
+			// inputs are also created by terragrunt units passing them
+			pos, found := ctx.findInModuleFiles(module, variableUsageRegexp(input.Name))
+			if callers := moduleCallers(ctx.State, module); !found && 0 != len(callers) {
+				pos = callers[0].Pos
+			}
+			findings = append(findings, LintFinding{Module: module.Name, Message: fmt.Sprintf("variable %s is not declared in module %s", input.Name, module.Name), Pos: pos})
+		}
+	}
+
+	// arguments of module calls, child inputs are only known to the flow graph
+	graph := NewFlowGraph(ctx.State)
+	reported := make(map[string]bool)
+	for _, edge := range graph.Edges {
+		if "AsModuleInput" != edge.Kind || reported[edge.To+edge.Label] {
+			continue
+		}
+		from, _ := graph.Node(edge.From)
+		to, _ := graph.Node(edge.To)
+		caller, callerFound := ctx.State.allModulesMap[from.Module]
+		child, childFound := ctx.State.allModulesMap[to.Module]
+		if !callerFound || !childFound || !child.IsLoaded || hasLoadedInput(child, to.Name) {
+			continue
+		}
+		instance := caller.FindModuleInstance(strings.TrimPrefix(edge.Label, "module."))
+		if nil == instance {
+			continue
+		}
+		reported[edge.To+edge.Label] = true
+		findings = append(findings, LintFinding{Module: caller.Name, Message: fmt.Sprintf("argument %s of module %s is not declared in module %s", to.Name, instance.InstanceName, child.Name), Pos: instance.Pos})
+	}
+	return findings
+}
+
+func hasLoadedInput(module *Module, name string) bool {
+	for _, input := range module.Inputs {
+		if input.Name == name && input.IsLoaded {
+			return true
+		}
+	}
+	return false
+}
+
+func variableUsageRegexp(name string) *regexp.Regexp {
+	return regexp.MustCompile("var\\." + regexp.QuoteMeta(name) + "([^-a-zA-Z0-9_]|$)")
+}
+
+// first line of the module files matching, only used to position findings
+func (ctx *lintContext) findInModuleFiles(module *Module, re *regexp.Regexp) (SourcePos, bool) {
+	for _, file := range ctx.moduleFiles(module) {
+		for i, line := range ctx.fileLines(file) {
+			if re.MatchString(line) {
+				return SourcePos{Filename: file, Line: i + 1, EndLine: i + 1}, true
+			}
+		}
+	}
+	return SourcePos{}, false
+}
+
+// comments and strings mentioning var.<name> are not usages, only references loaded into the hierarchy count
+func checkUnusedVariables(ctx *lintContext) []LintFinding {
+	var findings []LintFinding
+	graph := NewFlowGraph(ctx.State)
+	for _, module := range ctx.State.AllModules {
+		if !module.IsLoaded || nil != module.Terragrunt {
+			continue
+		}
+		for _, input := range module.Inputs {
+			if input.IsLoaded && !isInputUsed(graph, module, input) {
+				findings = append(findings, LintFinding{Module: module.Name, Message: fmt.Sprintf("variable %s is not used", input.Name), Pos: input.Pos})
+			}
+		}
+	}
+	return findings
+}
+
+// arguments, module arguments, locals and outputs are flow graph edges, provider and remote state configs are kept as text
+func isInputUsed(graph *FlowGraph, module *Module, input *ModuleInput) bool {
+	if 0 != len(input.AsArgument) || 0 != len(input.AsModuleInput) || 0 != len(graph.Downstream(inputNodeID(module.Name, input.Name))) {
+		return true
+	}
+
+	var configs []map[string]string
+	for _, provider := range module.Providers {
+		configs = append(configs, provider.Arguments)
+	}
+	for _, remoteState := range module.RemoteStates {
+		configs = append(configs, remoteState.Config)
+	}
+	for _, config := range configs {
+		for _, value := range config {
+			for _, variable := range findAllVariables(value) {
+				if input.Name == string(variable) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func checkMissingModules(ctx *lintContext) []LintFinding {
+	var findings []LintFinding
+	for _, module := range ctx.State.AllModules {
+		for _, instance := range module.ModuleInstances {
+			isLocal := strings.HasPrefix(instance.Source, "./") || strings.HasPrefix(instance.Source, "../") || filepath.IsAbs(instance.Source)
+			if isLocal && nil != instance.Instance && !instance.Instance.IsLoaded {
+				findings = append(findings, LintFinding{Module: module.Name, Message: fmt.Sprintf("module %s: source %s is not found", instance.InstanceName, instance.Source), Pos: instance.Pos})
+			}
+		}
+	}
+	return findings
+}
+
+func checkMissingDescriptions(ctx *lintContext) []LintFinding {
+	var findings []LintFinding
+	for _, module := range ctx.State.AllModules {
+		if !module.IsLoaded {
+			continue
+		}
+		for _, input := range module.Inputs {
+			if input.IsLoaded && "" == input.Description {
+				findings = append(findings, LintFinding{Module: module.Name, Message: fmt.Sprintf("variable %s has no description", input.Name), Pos: input.Pos})
+			}
+		}
+		for _, output := range module.Outputs {
+			if output.IsLoaded && "" == output.Description {
+				findings = append(findings, LintFinding{Module: module.Name, Message: fmt.Sprintf("output %s has no description", output.Name), Pos: output.Pos})
+			}
+		}
+	}
+	return findings
+}
+
+func checkSensitiveFlows(ctx *lintContext) []LintFinding {
+	var findings []LintFinding
+	graph := NewFlowGraph(ctx.State)
+	for _, flow := range FindSensitiveFlows(ctx.State, ctx.Resources, defaultSensitiveSinks) {
+		node, _ := graph.Node(flow.Sink)
+		message := fmt.Sprintf("sensitive %s reaches output %s not marked sensitive", flow.Source, node.Name)
+		if "argument" == flow.SinkKind {
+			message = fmt.Sprintf("sensitive %s reaches argument %s of %s", flow.Source, flow.Argument, node.Name)
+		}
+		findings = append(findings, LintFinding{Module: node.Module, Message: message, Pos: flow.Pos})
+	}
+	return findings
+}
+
+func checkUnsatisfiableVersions(ctx *lintContext) []LintFinding {
+	var findings []LintFinding
+	for _, report := range AnalyzeVersionConstraints(ctx.State) {
+		root := ctx.State.allModulesMap[report.Root]
+		pos := SourcePos{}
+		if nil != root && nil != root.Terraform {
+			pos = root.Terraform.Pos
+		}
+		for _, requirement := range report.Requirements {
+			if !requirement.Satisfiable {
+				findings = append(findings, LintFinding{Module: report.Root, Message: fmt.Sprintf("%s constraints can not be met together: %s", requirement.Name, requirement.Effective), Pos: pos})
+			}
+		}
+	}
+	return findings
+}
+
+// module calls of the module from every other module
+func moduleCallers(state *HierarchyState, module *Module) []*ModuleInstance {
+	var result []*ModuleInstance
+	for _, caller := range state.AllModules {
+		for _, instance := range caller.ModuleInstances {
+			if instance.Instance == module {
+				result = append(result, instance)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRunLint(t *testing.T) {
+	Convey("Lint rules must report findings with positions and honour ignores", t, func() {
+		state := loadTestState("testdata/lint")
+		config, err := readLintConfig("testdata/lint/" + hierarchyLintFile)
+		So(err, ShouldBeNil)
+
+		findings := RunLint(state, nil, config)
+		byRule := make(map[string][]LintFinding)
+		for _, finding := range findings {
+			byRule[finding.Rule] = append(byRule[finding.Rule], finding)
+		}
+
+		So(len(byRule["invalid-file"]), ShouldEqual, 1)
+		So(byRule["invalid-file"][0].Module, ShouldEqual, "broken")
+
+		// comments and descriptions naming a variable are not usages, outputs and data sources are
+		unused := byRule["unused-variable"]
+		So(len(unused), ShouldEqual, 3)
+		So(unused[0].Module, ShouldEqual, ".")
+		So(unused[0].Pos.Line, ShouldEqual, 10)
+		So(unused[1].Message, ShouldEqual, "variable commented is not used")
+		So(unused[2].Message, ShouldEqual, "variable documented is not used")
+
+		undeclared := byRule["undeclared-variable"]
+		So(len(undeclared), ShouldEqual, 2)
+		So(undeclared[0].Message, ShouldEqual, "argument zone of module app is not declared in module modules.app")
+		So(undeclared[0].Pos.Line, ShouldEqual, 14)
+		So(undeclared[1].Module, ShouldEqual, "modules.app")
+		So(undeclared[1].Message, ShouldEqual, "variable key_name is not declared in module modules.app")
+		So(undeclared[1].Pos.Line, ShouldEqual, 7)
+
+		So(len(byRule["missing-module"]), ShouldEqual, 1)
+		So(byRule["missing-module"][0].Module, ShouldEqual, ".")
+		So(len(byRule["missing-description"]), ShouldEqual, 1)
+		So(byRule["missing-description"][0].Severity, ShouldEqual, severityWarning)
+		So(lintError(findings), ShouldNotBeNil)
+
+		config.Severities["missing-module"] = severityOff
+		config.Severities["invalid-file"] = severityWarning
+		config.Severities["undeclared-variable"] = severityInfo
+		findings = RunLint(state, nil, config)
+		for _, finding := range findings {
+			So(finding.Rule, ShouldNotEqual, "missing-module")
+		}
+		So(lintError(findings), ShouldBeNil)
+	})
+
+	Convey("Malformed lint config must be rejected", t, func() {
+		dir, err := ioutil.TempDir("", "hierarchy-lint")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, hierarchyLintFile)
+		So(ioutil.WriteFile(path, []byte("unused-variable = loud\n"), 0644), ShouldBeNil)
+		_, err = readLintConfig(path)
+		So(err, ShouldNotBeNil)
+
+		config, err := readLintConfig(filepath.Join(dir, "missing"))
+		So(err, ShouldBeNil)
+		So(config.Option("naming", "variable", "x"), ShouldEqual, "x")
+	})
+}
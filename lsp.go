@@ -0,0 +1,580 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// language server protocol messages (only the fields we need)
+type lspRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+	Error   *lspError       `json:"error,omitempty"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type lspTextDocument struct {
+	URI string `json:"uri"`
+}
+
+type lspPositionParams struct {
+	TextDocument lspTextDocument `json:"textDocument"`
+	Position     lspPosition     `json:"position"`
+	Context      struct {
+		IncludeDeclaration bool `json:"includeDeclaration"`
+	} `json:"context"`
+}
+
+type lspMarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type lspHover struct {
+	Contents lspMarkupContent `json:"contents"`
+}
+
+type lspInitializeParams struct {
+	RootURI  string `json:"rootUri"`
+	RootPath string `json:"rootPath"`
+}
+
+const (
+	lspParseError     = -32700
+	lspMethodNotFound = -32601
+	lspInvalidParams  = -32602
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// server
+type lspServer struct {
+	awsResources []Resource
+	// workspace root sent by the client, -dir until then
+	root    string
+	watcher *moduleWatcher
+	state   *HierarchyState
+	in      *bufio.Reader
+	out     io.Writer
+}
+
+func runLSP(args []string) error {
+	awsResources, err := loadResources(*descriptionPath)
+	if err != nil {
+		return fmt.Errorf("error loading aws resources: %v", err)
+	}
+
+	// stdout is the protocol channel
+	log.SetOutput(os.Stderr)
+
+	server := &lspServer{awsResources: awsResources, in: bufio.NewReader(os.Stdin), out: os.Stdout}
+	err = server.open(*rootDir)
+	if nil != err {
+		return err
+	}
+	return server.serve()
+}
+
+// loads the hierarchy of the workspace root
+func (s *lspServer) open(root string) error {
+	watcher := newModuleWatcher(root, s.awsResources)
+	state, err := watcher.Load()
+	if nil != err {
+		return err
+	}
+	s.root = root
+	s.watcher = watcher
+	s.state = state
+	return nil
+}
+
+// the client may open another directory than -dir
+func (s *lspServer) initialize(params json.RawMessage) *lspError {
+	var initialize lspInitializeParams
+	if 0 != len(params) {
+		if err := json.Unmarshal(params, &initialize); nil != err {
+			return &lspError{Code: lspInvalidParams, Message: err.Error()}
+		}
+	}
+	root := initialize.RootPath
+	if "" != initialize.RootURI {
+		root = uriToPath(initialize.RootURI)
+	}
+	if "" == root || pathToURI(root) == pathToURI(s.root) {
+		return nil
+	}
+
+	log.Infof("lsp: loading workspace %s", root)
+	if err := s.open(root); nil != err {
+		return &lspError{Code: lspInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *lspServer) serve() error {
+	for {
+		request, err := s.readMessage()
+		if io.EOF == err {
+			return nil
+		}
+		if nil != err {
+			return err
+		}
+
+		result, rpcErr := s.handle(request)
+		if "exit" == request.Method {
+			return nil
+		}
+		if 0 == len(request.ID) {
+			// notification
+			continue
+		}
+
+		err = s.writeMessage(lspResponse{JSONRPC: "2.0", ID: request.ID, Result: result, Error: rpcErr})
+		if nil != err {
+			return err
+		}
+	}
+}
+
+func (s *lspServer) readMessage() (*lspRequest, error) {
+	length := -1
+	for {
+		line, err := s.in.ReadString('\n')
+		if nil != err {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if "" == line {
+			break
+		}
+		if strings.HasPrefix(strings.ToLower(line), "content-length:") {
+			length, err = strconv.Atoi(strings.TrimSpace(line[len("content-length:"):]))
+			if nil != err {
+				return nil, fmt.Errorf("lsp: bad content length: %v", err)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("lsp: message without content length")
+	}
+
+	body := make([]byte, length)
+	_, err := io.ReadFull(s.in, body)
+	if nil != err {
+		return nil, err
+	}
+
+	var request lspRequest
+	err = json.Unmarshal(body, &request)
+	if nil != err {
+		s.writeMessage(lspResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &lspError{Code: lspParseError, Message: err.Error()}})
+		return &lspRequest{}, nil
+	}
+	return &request, nil
+}
+
+func (s *lspServer) writeMessage(message interface{}) error {
+	body, err := json.Marshal(message)
+	if nil != err {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (s *lspServer) handle(request *lspRequest) (interface{}, *lspError) {
+	switch request.Method {
+	case "initialize":
+		if rpcErr := s.initialize(request.Params); nil != rpcErr {
+			return nil, rpcErr
+		}
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   map[string]interface{}{"openClose": true, "change": 0, "save": true},
+				"definitionProvider": true,
+				"referencesProvider": true,
+				"hoverProvider":      true,
+			},
+			"serverInfo": map[string]string{"name": "terraform-hierarchy"},
+		}, nil
+	case "shutdown":
+		return nil, nil
+	case "textDocument/didSave", "textDocument/didOpen":
+		s.refresh()
+		return nil, nil
+	case "textDocument/definition", "textDocument/references", "textDocument/hover":
+		var params lspPositionParams
+		if err := json.Unmarshal(request.Params, &params); nil != err {
+			return nil, &lspError{Code: lspInvalidParams, Message: err.Error()}
+		}
+		symbol := s.symbolAt(params.TextDocument.URI, params.Position)
+		if nil == symbol {
+			return nil, nil
+		}
+		switch request.Method {
+		case "textDocument/definition":
+			return s.definition(symbol), nil
+		case "textDocument/references":
+			return s.references(symbol, params.Context.IncludeDeclaration), nil
+		default:
+			return s.hover(symbol), nil
+		}
+	default:
+		if 0 == len(request.ID) || strings.HasPrefix(request.Method, "$/") {
+			return nil, nil
+		}
+		return nil, &lspError{Code: lspMethodNotFound, Message: "method not supported: " + request.Method}
+	}
+}
+
+// saved files are picked up by the watcher, only their modules are rebuilt
+func (s *lspServer) refresh() {
+	changes, err := s.watcher.Scan()
+	if nil != err {
+		log.Error("lsp: ", err)
+		return
+	}
+	if !changes.IsEmpty() {
+		s.watcher.Apply(s.state, changes)
+	}
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// symbols
+type lspSymbol struct {
+	// variable, output, module, module-argument
+	Kind string
+	// module the file under cursor belongs to
+	Module *Module
+	// variable/output name, or module instance name
+	Name string
+	// output of the module instance, or argument name of the module block
+	Field string
+}
+
+var (
+	lspVariableRegexp    = regexp.MustCompile("var\\.([-a-zA-Z0-9_]+)")
+	lspModuleRegexp      = regexp.MustCompile("module\\.([-a-zA-Z0-9_]+)(?:\\.([-a-zA-Z0-9_]+))?")
+	lspDeclarationRegexp = regexp.MustCompile("^\\s*(variable|output|module)\\s+\"([^\"]+)\"")
+	lspArgumentRegexp    = regexp.MustCompile("^\\s*\"?([-a-zA-Z0-9_]+)\"?\\s*=")
+)
+
+func uriToPath(uri string) string {
+	parsed, err := url.Parse(uri)
+	if nil != err || "file" != parsed.Scheme {
+		return uri
+	}
+	return parsed.Path
+}
+
+func pathToURI(path string) string {
+	abs, err := filepath.Abs(path)
+	if nil != err {
+		abs = path
+	}
+	return (&url.URL{Scheme: "file", Path: abs}).String()
+}
+
+func readLine(path string, line int) string {
+	bytes, err := ioutil.ReadFile(path)
+	if nil != err {
+		return ""
+	}
+	lines := strings.Split(string(bytes), "\n")
+	if line < 0 || line >= len(lines) {
+		return ""
+	}
+	return lines[line]
+}
+
+// characters are treated as bytes, which is exact for ascii terraform code
+func (s *lspServer) symbolAt(uri string, position lspPosition) *lspSymbol {
+	path := uriToPath(uri)
+	module := findDirModule(s.state, s.root, filepath.Dir(path))
+	if nil == module {
+		return nil
+	}
+	line := readLine(path, position.Line)
+
+	inside := func(match []int) bool {
+		return match[0] <= position.Character && position.Character <= match[1]
+	}
+
+	for _, match := range lspVariableRegexp.FindAllStringSubmatchIndex(line, -1) {
+		if inside(match) {
+			return &lspSymbol{Kind: "variable", Module: module, Name: line[match[2]:match[3]]}
+		}
+	}
+	for _, match := range lspModuleRegexp.FindAllStringSubmatchIndex(line, -1) {
+		if inside(match) {
+			symbol := &lspSymbol{Kind: "module", Module: module, Name: line[match[2]:match[3]]}
+			if match[4] >= 0 {
+				symbol.Kind = "output"
+				symbol.Field = line[match[4]:match[5]]
+			}
+			return symbol
+		}
+	}
+	if match := lspDeclarationRegexp.FindStringSubmatch(line); nil != match {
+		switch match[1] {
+		case "variable":
+			return &lspSymbol{Kind: "variable", Module: module, Name: match[2]}
+		case "output":
+			return &lspSymbol{Kind: "output", Module: module, Field: match[2]}
+		default:
+			return &lspSymbol{Kind: "module", Module: module, Name: match[2]}
+		}
+	}
+
+	if match := lspArgumentRegexp.FindStringSubmatch(line); nil != match && "source" != match[1] {
+		abs, _ := filepath.Abs(path)
+		for _, instance := range module.ModuleInstances {
+			filename, _ := filepath.Abs(instance.Pos.Filename)
+			if filename == abs && instance.Pos.Contains(position.Line+1) {
+				return &lspSymbol{Kind: "module-argument", Module: module, Name: instance.InstanceName, Field: match[1]}
+			}
+		}
+	}
+	return nil
+}
+
+// module declaring the symbol and the declared name
+func (s *lspServer) target(symbol *lspSymbol) (*Module, string) {
+	if ("output" == symbol.Kind && "" == symbol.Name) || "variable" == symbol.Kind {
+		name := symbol.Name
+		if "output" == symbol.Kind {
+			name = symbol.Field
+		}
+		return symbol.Module, name
+	}
+
+	instance := symbol.Module.FindModuleInstance(symbol.Name)
+	if nil == instance {
+		return nil, ""
+	}
+	return s.state.allModulesMap[instance.ModulePath], symbol.Field
+}
+
+func (s *lspServer) definition(symbol *lspSymbol) []lspLocation {
+	if "module" == symbol.Kind {
+		instance := symbol.Module.FindModuleInstance(symbol.Name)
+		if nil == instance {
+			return nil
+		}
+		return []lspLocation{posLocation(instance.Pos)}
+	}
+
+	module, name := s.target(symbol)
+	if nil == module {
+		return nil
+	}
+
+	if "output" == symbol.Kind {
+		for _, output := range module.Outputs {
+			if output.Name == name && output.IsLoaded {
+				return []lspLocation{posLocation(output.Pos)}
+			}
+		}
+		return nil
+	}
+
+	for _, input := range module.Inputs {
+		if input.Name == name && input.IsLoaded {
+			return []lspLocation{posLocation(input.Pos)}
+		}
+	}
+	return nil
+}
+
+func (s *lspServer) references(symbol *lspSymbol, includeDeclaration bool) []lspLocation {
+	module, name := s.target(symbol)
+	if nil == module {
+		return nil
+	}
+
+	result := make([]lspLocation, 0)
+	if includeDeclaration {
+		result = append(result, s.definition(symbol)...)
+	}
+
+	parents := moduleParents(s.state)
+	if "output" == symbol.Kind {
+		// module.<instance>.<output> in every caller
+		for _, parentName := range parents[module.Name] {
+			parent := s.state.allModulesMap[parentName]
+			for _, instance := range parent.ModuleInstances {
+				if instance.ModulePath == module.Name {
+					pattern := regexp.MustCompile("(module\\." + regexp.QuoteMeta(instance.InstanceName) + "\\." + regexp.QuoteMeta(name) + ")(?:[^-a-zA-Z0-9_]|$)")
+					result = append(result, findInModuleFiles(s.root, parent, pattern)...)
+				}
+			}
+		}
+		return result
+	}
+
+	if "variable" != symbol.Kind && "module-argument" != symbol.Kind {
+		return result
+	}
+
+	result = append(result, findInModuleFiles(s.root, module, regexp.MustCompile("(var\\."+regexp.QuoteMeta(name)+")(?:[^-a-zA-Z0-9_]|$)"))...)
+
+	// arguments of module blocks passing the variable
+	for _, parentName := range parents[module.Name] {
+		parent := s.state.allModulesMap[parentName]
+		for _, instance := range parent.ModuleInstances {
+			if instance.ModulePath != module.Name {
+				continue
+			}
+			for _, location := range findInModuleFiles(s.root, parent, regexp.MustCompile("^\\s*\"?("+regexp.QuoteMeta(name)+")\"?\\s*=")) {
+				filename, _ := filepath.Abs(instance.Pos.Filename)
+				if pathToURI(filename) == location.URI && instance.Pos.Contains(location.Range.Start.Line+1) {
+					result = append(result, location)
+				}
+			}
+		}
+	}
+	return result
+}
+
+func (s *lspServer) hover(symbol *lspSymbol) *lspHover {
+	module, name := s.target(symbol)
+	if nil == module {
+		return nil
+	}
+
+	var buffer bytes.Buffer
+	switch symbol.Kind {
+	case "variable", "module-argument":
+		for _, input := range module.Inputs {
+			if input.Name != name {
+				continue
+			}
+			fmt.Fprintf(&buffer, "**var.%s** in module `%s`\n\n", input.Name, module.Name)
+			if "" != input.Type {
+				fmt.Fprintf(&buffer, "type: `%s`  \n", input.Type)
+			}
+			if input.Required {
+				buffer.WriteString("required  \n")
+			} else {
+				fmt.Fprintf(&buffer, "default: `%s`  \n", input.Default)
+			}
+			if "" != input.Description {
+				fmt.Fprintf(&buffer, "\n%s\n", input.Description)
+			}
+
+			flows := findInputFlows(s.state, module, input, nil)
+			if len(flows) > 0 {
+				buffer.WriteString("\nLands in:\n")
+				for _, flow := range flows {
+					fmt.Fprintf(&buffer, "* `%s`", flow.String())
+					if nil != flow.Argument && "" != flow.Argument.Description {
+						fmt.Fprintf(&buffer, " - %s", flow.Argument.Description)
+					}
+					buffer.WriteString("\n")
+				}
+			}
+		}
+	case "output":
+		for _, output := range module.Outputs {
+			if output.Name != name {
+				continue
+			}
+			fmt.Fprintf(&buffer, "**output %s** of module `%s`\n\n", output.Name, module.Name)
+			if "" != output.Description {
+				fmt.Fprintf(&buffer, "%s\n\n", output.Description)
+			}
+			for _, edge := range NewFlowGraph(s.state).Upstream(outputNodeID(module.Name, output.Name)) {
+				fmt.Fprintf(&buffer, "* from `%s` (%s %s)\n", edge.From, edge.Kind, edge.Label)
+			}
+		}
+	case "module":
+		instance := symbol.Module.FindModuleInstance(symbol.Name)
+		if nil != instance {
+			fmt.Fprintf(&buffer, "**module.%s** is `%s`\n", instance.InstanceName, instance.ModulePath)
+		}
+	}
+
+	if 0 == buffer.Len() {
+		return nil
+	}
+	return &lspHover{Contents: lspMarkupContent{Kind: "markdown", Value: buffer.String()}}
+}
+
+func posLocation(pos SourcePos) lspLocation {
+	line := pos.Line - 1
+	if line < 0 {
+		line = 0
+	}
+	return lspLocation{URI: pathToURI(pos.Filename), Range: lspRange{Start: lspPosition{Line: line}, End: lspPosition{Line: line}}}
+}
+
+// matches of the first pattern group in every module file
+func findInModuleFiles(root string, module *Module, pattern *regexp.Regexp) []lspLocation {
+	var result []lspLocation
+
+	dir := filepath.Join(root, module.Path)
+	files, err := ioutil.ReadDir(dir)
+	if nil != err {
+		return result
+	}
+
+	for _, file := range files {
+		path := filepath.Join(dir, file.Name())
+		if file.IsDir() || !isModuleFileName(path) {
+			continue
+		}
+		bytes, err := ioutil.ReadFile(path)
+		if nil != err {
+			continue
+		}
+		for i, line := range strings.Split(string(bytes), "\n") {
+			for _, match := range pattern.FindAllStringSubmatchIndex(line, -1) {
+				result = append(result, lspLocation{
+					URI:   pathToURI(path),
+					Range: lspRange{Start: lspPosition{Line: i, Character: match[2]}, End: lspPosition{Line: i, Character: match[3]}},
+				})
+			}
+		}
+	}
+	return result
+}
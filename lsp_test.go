@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLSPReadMessage(t *testing.T) {
+	Convey("Messages must be framed by their Content-Length header", t, func() {
+		body := `{"jsonrpc":"2.0","id":1,"method":"initialize"}`
+		cases := []struct {
+			input  string
+			method string
+			err    error
+			failed bool
+		}{
+			{input: "Content-Length: 46\r\n\r\n" + body, method: "initialize"},
+			{input: "content-length: 46\r\nContent-Type: application/vscode-jsonrpc\r\n\r\n" + body, method: "initialize"},
+			{input: "Content-Length: 46\r\n\r\n" + body + "Content-Length: 2\r\n\r\n{}", method: "initialize"},
+			// truncated header and body
+			{input: "Content-Length: 46\r\n", err: io.EOF},
+			{input: "Content-Length: 46\r\n\r\n" + body[:20], err: io.ErrUnexpectedEOF},
+			{input: "Content-Type: application/vscode-jsonrpc\r\n\r\n" + body, failed: true},
+			{input: "Content-Length: many\r\n\r\n" + body, failed: true},
+		}
+
+		for _, c := range cases {
+			server := &lspServer{in: bufio.NewReader(strings.NewReader(c.input)), out: &bytes.Buffer{}}
+			request, err := server.readMessage()
+			switch {
+			case nil != c.err:
+				So(err, ShouldEqual, c.err)
+			case c.failed:
+				So(err, ShouldNotBeNil)
+			default:
+				So(err, ShouldBeNil)
+				So(request.Method, ShouldEqual, c.method)
+			}
+		}
+	})
+
+	Convey("Malformed json must be answered with a parse error", t, func() {
+		out := &bytes.Buffer{}
+		server := &lspServer{in: bufio.NewReader(strings.NewReader("Content-Length: 5\r\n\r\n{oops")), out: out}
+		request, err := server.readMessage()
+		So(err, ShouldBeNil)
+		So(request.Method, ShouldEqual, "")
+		So(out.String(), ShouldContainSubstring, `"code":-32700`)
+	})
+}
+
+func TestLSPSymbols(t *testing.T) {
+	state := loadTestState("testdata/lsp")
+	server := &lspServer{root: "testdata/lsp", state: state}
+	root := pathToURI("testdata/lsp/main.tf")
+	app := pathToURI("testdata/lsp/modules/app/main.tf")
+
+	Convey("Symbols under the cursor must be recognized", t, func() {
+		cases := []struct {
+			uri      string
+			position lspPosition
+			kind     string
+			module   string
+			name     string
+			field    string
+		}{
+			{uri: root, position: lspPosition{Line: 0, Character: 12}, kind: "variable", module: ".", name: "ami"},
+			{uri: root, position: lspPosition{Line: 6, Character: 16}, kind: "variable", module: ".", name: "ami"},
+			{uri: root, position: lspPosition{Line: 4, Character: 0}, kind: "module", module: ".", name: "app"},
+			{uri: root, position: lspPosition{Line: 6, Character: 3}, kind: "module-argument", module: ".", name: "app", field: "image"},
+			{uri: root, position: lspPosition{Line: 10, Character: 20}, kind: "output", module: ".", name: "app", field: "id"},
+			{uri: root, position: lspPosition{Line: 9, Character: 0}, kind: "output", module: ".", field: "app_id"},
+			{uri: app, position: lspPosition{Line: 5, Character: 14}, kind: "variable", module: "modules.app", name: "image"},
+			// source of a module block and places outside of any symbol
+			{uri: root, position: lspPosition{Line: 5, Character: 3}},
+			{uri: root, position: lspPosition{Line: 3, Character: 0}},
+		}
+
+		for _, c := range cases {
+			symbol := server.symbolAt(c.uri, c.position)
+			if "" == c.kind {
+				So(symbol, ShouldBeNil)
+				continue
+			}
+			So(symbol, ShouldNotBeNil)
+			So(symbol.Kind, ShouldEqual, c.kind)
+			So(symbol.Module.Name, ShouldEqual, c.module)
+			So(symbol.Name, ShouldEqual, c.name)
+			So(symbol.Field, ShouldEqual, c.field)
+		}
+	})
+
+	Convey("Definitions must point to the declaring module", t, func() {
+		cases := []struct {
+			position lspPosition
+			uri      string
+			line     int
+		}{
+			{position: lspPosition{Line: 6, Character: 16}, uri: root, line: 0},
+			{position: lspPosition{Line: 6, Character: 3}, uri: app, line: 0},
+			{position: lspPosition{Line: 10, Character: 20}, uri: app, line: 8},
+			{position: lspPosition{Line: 4, Character: 8}, uri: root, line: 4},
+		}
+
+		for _, c := range cases {
+			locations := server.definition(server.symbolAt(root, c.position))
+			So(len(locations), ShouldEqual, 1)
+			So(locations[0].URI, ShouldEqual, c.uri)
+			So(locations[0].Range.Start.Line, ShouldEqual, c.line)
+		}
+	})
+
+	Convey("References must include callers passing the variable and reading the output", t, func() {
+		symbol := server.symbolAt(app, lspPosition{Line: 0, Character: 12})
+		references := server.references(symbol, true)
+		So(len(references), ShouldEqual, 3)
+		So(references[0].URI, ShouldEqual, app)
+		So(references[0].Range.Start.Line, ShouldEqual, 0)
+		So(references[1].URI, ShouldEqual, app)
+		So(references[1].Range.Start, ShouldResemble, lspPosition{Line: 5, Character: 11})
+		So(references[2].URI, ShouldEqual, root)
+		So(references[2].Range.Start, ShouldResemble, lspPosition{Line: 6, Character: 2})
+
+		references = server.references(server.symbolAt(app, lspPosition{Line: 8, Character: 0}), false)
+		So(len(references), ShouldEqual, 1)
+		So(references[0].URI, ShouldEqual, root)
+		So(references[0].Range.Start, ShouldResemble, lspPosition{Line: 10, Character: 13})
+	})
+
+	Convey("Hover must describe the symbol and where its value goes", t, func() {
+		hover := server.hover(server.symbolAt(root, lspPosition{Line: 6, Character: 16}))
+		So(hover, ShouldNotBeNil)
+		So(hover.Contents.Kind, ShouldEqual, "markdown")
+		So(hover.Contents.Value, ShouldContainSubstring, "**var.ami** in module `.`")
+		So(hover.Contents.Value, ShouldContainSubstring, "required")
+		So(hover.Contents.Value, ShouldContainSubstring, "image of the web servers")
+		So(hover.Contents.Value, ShouldContainSubstring, "Lands in:")
+
+		hover = server.hover(server.symbolAt(root, lspPosition{Line: 10, Character: 20}))
+		So(hover, ShouldNotBeNil)
+		So(hover.Contents.Value, ShouldContainSubstring, "**output id** of module `modules.app`")
+		So(hover.Contents.Value, ShouldContainSubstring, "instance id")
+		So(hover.Contents.Value, ShouldContainSubstring, "FromAttribute id")
+
+		hover = server.hover(server.symbolAt(root, lspPosition{Line: 4, Character: 0}))
+		So(hover.Contents.Value, ShouldEqual, "**module.app** is `modules.app`\n")
+	})
+}
+
+func TestLSPWorkspaceRoot(t *testing.T) {
+	Convey("Modules must be looked up in the workspace root sent by the client", t, func() {
+		state := loadTestState("testdata/docs")
+		server := &lspServer{root: "testdata/docs", state: state}
+		uri := pathToURI("testdata/lsp/modules/app/main.tf")
+		So(server.symbolAt(uri, lspPosition{Line: 0, Character: 12}), ShouldBeNil)
+
+		params := json.RawMessage(`{"rootUri":"` + pathToURI("testdata/lsp") + `"}`)
+		_, rpcErr := server.handle(&lspRequest{JSONRPC: "2.0", ID: json.RawMessage("1"), Method: "initialize", Params: params})
+		So(rpcErr, ShouldBeNil)
+		So(server.root, ShouldEqual, uriToPath(pathToURI("testdata/lsp")))
+
+		// -dir of the command line is not used any more
+		*rootDir = "testdata/docs"
+		symbol := server.symbolAt(uri, lspPosition{Line: 0, Character: 12})
+		So(symbol, ShouldNotBeNil)
+		So(symbol.Module.Name, ShouldEqual, "modules.app")
+		So(len(server.references(symbol, false)), ShouldEqual, 2)
+	})
+}
@@ -5,6 +5,9 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/vharitonsky/iniflags"
@@ -14,12 +17,29 @@ var (
 	rootDir         = flag.String("dir", ".", "start dir")
 	descriptionPath = flag.String("desc", "", "terraform markdown description")
 	outPath         = flag.String("out", "", "output result filepath")
+	outFormat       = flag.String("format", "json", "output format: json, html (dump), sarif, junit (lint)")
+	jobs            = flag.Int("j", runtime.NumCPU(), "number of files parsed in parallel")
+	cacheDir        = flag.String("cache-dir", hierarchyCacheDir, "parse cache directory, relative to -dir, empty disables the cache")
 )
 
+var (
+	includeGlobs globList
+	excludeGlobs globList
+)
+
+func init() {
+	flag.Var(&includeGlobs, "include", "load only module directories matching the glob (with their subdirectories), may be repeated")
+	flag.Var(&excludeGlobs, "exclude", "skip directories and files matching the glob, may be repeated")
+}
+
+// tool version, set with -ldflags "-X main.version=..."
+var version = "dev"
+
 type Line struct {
 	Name        string `form:"Name" json:"Name" xml:"Name"`
 	Optional    bool   `form:"Optional" json:"Optional" xml:"Optional"`
 	Description string `form:"Description" json:"Description" xml:"Description"`
+	Sensitive   bool   `form:"Sensitive" json:"Sensitive,omitempty" xml:"Sensitive"`
 }
 
 type ResourceArgument Line
@@ -31,15 +51,80 @@ type Resource struct {
 	Attributes []ResourceAttribute `form:"Attributes" json:"Attributes" xml:"Attributes"`
 }
 
+// commands are selected by the first positional argument, the rest is parsed by the command itself
+type command struct {
+	Name        string
+	Description string
+	Run         func(args []string) error
+}
+
+var commands = []command{
+	{Name: "dump", Description: "dump the whole hierarchy as json (default)", Run: runDump},
+	{Name: "plan-impact", Description: "map 'terraform show -json' plan changes back onto module inputs", Run: runPlanImpact},
+	{Name: "impact", Description: "list modules and roots affected by .tf changes between git refs", Run: runImpact},
+	{Name: "diff", Description: "semantic diff between two dumps or terraform directories", Run: runDiff},
+	{Name: "compat", Description: "classify module interface changes and suggest a semver bump", Run: runCompat},
+	{Name: "providers", Description: "resolve the provider configuration every resource deploys with through module calls", Run: runProviders},
+	{Name: "versions", Description: "combine terraform and provider version constraints of every root and its modules", Run: runVersions},
+	{Name: "remote-states", Description: "link terraform_remote_state data sources to the roots writing the state", Run: runRemoteStates},
+	{Name: "sensitive", Description: "trace sensitive variables and attributes to outputs and arguments showing them in plain text", Run: runSensitive},
+	{Name: "lint", Description: "run lint rules over the hierarchy, fails on error findings", Run: runLint},
+	{Name: "roots", Description: "find every root module of the repository and dump them in one document", Run: runRoots},
+	{Name: "docs", Description: "write markdown interface documentation for every module", Run: runDocs},
+	{Name: "serve", Description: "load the hierarchy once and answer queries over a local json http api", Run: runServe},
+	{Name: "watch", Description: "watch .tf files, update the hierarchy incrementally and print change events", Run: runWatch},
+	{Name: "lsp", Description: "language server on stdio with cross-module definitions, references and hover", Run: runLSP},
+}
+
 func main() {
 	iniflags.Parse()
 	log.SetLevel(log.InfoLevel)
+
+	if "" != *cacheDir {
+		dir := *cacheDir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(*rootDir, dir)
+		}
+		cache, err := newParseCache(dir)
+		if nil != err {
+			log.Warning("parse cache is disabled: ", err)
+		} else {
+			moduleParseCache = cache
+		}
+	}
+
+	commandName := "dump"
+	var args []string
+	if flag.NArg() > 0 {
+		commandName = flag.Arg(0)
+		args = flag.Args()[1:]
+	}
+
+	for _, cmd := range commands {
+		if cmd.Name == commandName {
+			err := cmd.Run(args)
+			if nil != err {
+				log.Errorf("%s: %v", cmd.Name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	log.Errorf("unknown command '%s', available commands:", commandName)
+	for _, cmd := range commands {
+		log.Errorf("  %s - %s", cmd.Name, cmd.Description)
+	}
+	os.Exit(2)
+}
+
+// loads aws resource descriptions and the root module from command line flags
+func loadState() (*HierarchyState, []Resource, error) {
 	log.Debug("reading directory: ", *rootDir)
 
 	awsResources, err := loadResources(*descriptionPath)
 	if err != nil {
-		log.Error("error loading aws resources: ", err)
-		return
+		return nil, nil, fmt.Errorf("error loading aws resources: %v", err)
 	}
 
 	state := NewHierarchyState()
@@ -50,16 +135,41 @@ func main() {
 		log.Errorf("error reading root module '%s' (SKIPPED): %v", *rootDir, err)
 	}
 
-	jsonState, err := json.Marshal(*state)
-	if nil != err {
-		log.Error(err)
-	}
+	return state, awsResources, nil
+}
+
+func writeOutput(data []byte) error {
 	if "" != *outPath {
-		err = ioutil.WriteFile(*outPath, jsonState, 0755)
+		err := ioutil.WriteFile(*outPath, data, 0755)
 		if nil != err {
-			log.Errorf("writing to file (%s) error: %v", *outPath, err)
+			return fmt.Errorf("writing to file (%s) error: %v", *outPath, err)
 		}
 	} else {
-		fmt.Print(string(jsonState))
+		fmt.Print(string(data))
+	}
+	return nil
+}
+
+func runDump(args []string) error {
+	state, _, err := loadState()
+	if nil != err {
+		return err
+	}
+
+	switch *outFormat {
+	case "json":
+		jsonState, err := json.Marshal(state)
+		if nil != err {
+			return err
+		}
+		return writeOutput(jsonState)
+	case "html":
+		htmlState, err := WriteHTML(state, "terraform hierarchy: "+*rootDir)
+		if nil != err {
+			return err
+		}
+		return writeOutput(htmlState)
+	default:
+		return fmt.Errorf("unknown output format '%s'", *outFormat)
 	}
 }
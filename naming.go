@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// naming conventions, patterns are set in .hierarchylint:
+//
+//	naming-convention.variable = ^[a-z][a-z0-9_]*$
+//	naming-convention.output =
+//
+// an empty pattern turns the check of that kind off
+const namingConventionRule = "naming-convention"
+
+const defaultNamingPattern = "^[a-z][a-z0-9_]*$"
+
+// kinds of names checked, in report order
+var namingKinds = []string{"variable", "output", "resource", "module"}
+
+func checkNamingConventions(ctx *lintContext) []LintFinding {
+	var findings []LintFinding
+
+	patterns := make(map[string]*regexp.Regexp)
+	for _, kind := range namingKinds {
+		pattern := ctx.Config.Option(namingConventionRule, kind, defaultNamingPattern)
+		if "" == pattern {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if nil != err {
+			findings = append(findings, LintFinding{Message: fmt.Sprintf("%s.%s: invalid pattern: %v", namingConventionRule, kind, err)})
+			continue
+		}
+		patterns[kind] = re
+	}
+
+	check := func(module *Module, kind string, name string, pos SourcePos) {
+		if re, found := patterns[kind]; found && !re.MatchString(name) {
+			findings = append(findings, LintFinding{Module: module.Name, Message: fmt.Sprintf("%s name %s does not match %s", kind, name, re.String()), Pos: pos})
+		}
+	}
+
+	for _, module := range ctx.State.AllModules {
+		if !module.IsLoaded {
+			continue
+		}
+		for _, input := range module.Inputs {
+			if input.IsLoaded {
+				check(module, "variable", input.Name, input.Pos)
+			}
+		}
+		for _, output := range module.Outputs {
+			if output.IsLoaded {
+				check(module, "output", output.Name, output.Pos)
+			}
+		}
+		for _, resource := range module.Resources {
+			check(module, "resource", resource.Name, resource.Pos)
+		}
+		for _, instance := range module.ModuleInstances {
+			if terragruntInstanceName != instance.InstanceName || nil == module.Terragrunt {
+				check(module, "module", instance.InstanceName, instance.Pos)
+			}
+		}
+	}
+	return findings
+}
+
+// variables passed as they are to a module argument of another name: region = "${var.aws_region}"
+func checkModuleInputNames(ctx *lintContext) []LintFinding {
+	var findings []LintFinding
+	for _, module := range ctx.State.AllModules {
+		if !module.IsLoaded {
+			continue
+		}
+		for _, input := range module.Inputs {
+			for _, usage := range input.AsModuleInput {
+				var arguments []string
+				for _, path := range usage.UsagePath {
+					if len(path) >= 2 && unquote(path[1]) != input.Name {
+						arguments = append(arguments, unquote(path[1]))
+					}
+				}
+				if nil == usage.Input {
+					continue
+				}
+				for _, argument := range sortedUnique(arguments) {
+					pos, direct := ctx.findInBlock(usage.Input.Pos, directArgumentRegexp(argument, input.Name))
+					if !direct {
+						continue
+					}
+					findings = append(findings, LintFinding{
+						Module:  module.Name,
+						Message: fmt.Sprintf("variable %s is passed to argument %s of module %s, the names should match", input.Name, argument, usage.Input.InstanceName),
+						Pos:     pos,
+					})
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// first line of the block matching
+func (ctx *lintContext) findInBlock(block SourcePos, re *regexp.Regexp) (SourcePos, bool) {
+	lines := ctx.fileLines(block.Filename)
+	for line := block.Line; line <= block.EndLine && line <= len(lines); line++ {
+		if line >= 1 && re.MatchString(lines[line-1]) {
+			return SourcePos{Filename: block.Filename, Line: line, EndLine: line}, true
+		}
+	}
+	return SourcePos{}, false
+}
+
+// argument = "${var.name}" or argument = var.name, json syntax included
+func directArgumentRegexp(argument string, variable string) *regexp.Regexp {
+	reference := "var\\." + regexp.QuoteMeta(variable)
+	value := strings.Join([]string{"\"\\$\\{" + reference + "\\}\"", reference}, "|")
+	return regexp.MustCompile("^\\s*\"?" + regexp.QuoteMeta(argument) + "\"?\\s*[=:]\\s*(?:" + value + ")\\s*,?\\s*$")
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func namingFindings(config *lintConfig, rule string) []string {
+	var result []string
+	for _, finding := range RunLint(loadTestState("testdata/naming"), nil, config) {
+		if finding.Rule == rule {
+			result = append(result, finding.Message)
+		}
+	}
+	return result
+}
+
+func TestNamingConventions(t *testing.T) {
+	Convey("Names must match the configured patterns", t, func() {
+		So(namingFindings(newLintConfig(), namingConventionRule), ShouldResemble, []string{
+			"variable name InstanceType does not match ^[a-z][a-z0-9_]*$",
+			"module name Web does not match ^[a-z][a-z0-9_]*$",
+			"resource name webIP does not match ^[a-z][a-z0-9_]*$",
+			"output name web-ip does not match ^[a-z][a-z0-9_]*$",
+		})
+
+		config := newLintConfig()
+		config.Options["naming-convention.output"] = "^[a-z][-a-z0-9]*$"
+		config.Options["naming-convention.resource"] = ""
+		config.Options["naming-convention.module"] = "("
+		findings := namingFindings(config, namingConventionRule)
+		So(len(findings), ShouldEqual, 2)
+		So(strings.HasPrefix(findings[0], "naming-convention.module: invalid pattern"), ShouldBeTrue)
+	})
+
+	Convey("Variables passed as is must keep the argument name", t, func() {
+		So(namingFindings(newLintConfig(), "module-input-name"), ShouldResemble, []string{
+			"variable aws_region is passed to argument region of module Web, the names should match",
+			"variable InstanceType is passed to argument instance_type of module Web, the names should match",
+		})
+	})
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/hashicorp/hcl/hcl/ast"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// on-disk cache of parsed files, entries are keyed by tool version, cache format and file content so they never have to be invalidated
+//
+// builds without a version (dev) share entries, bump the format whenever parsed trees change:
+// parseModuleFile, parseJSONModuleFile, the hcl library or the gob encoding
+const parseCacheFormat = "2"
+
+// default cache directory in the terraform root
+const hierarchyCacheDir = ".hierarchy-cache"
+
+// set in main when caching is enabled
+var moduleParseCache *parseCache
+
+type parseCache struct {
+	dir    string
+	hits   int64
+	misses int64
+}
+
+func init() {
+	gob.Register(&ast.ObjectList{})
+	gob.Register(&ast.ObjectItem{})
+	gob.Register(&ast.ObjectKey{})
+	gob.Register(&ast.ObjectType{})
+	gob.Register(&ast.LiteralType{})
+	gob.Register(&ast.ListType{})
+}
+
+// the directory is created with the first entry written
+func newParseCache(dir string) (*parseCache, error) {
+	dir, err := filepath.Abs(dir)
+	if nil != err {
+		return nil, fmt.Errorf("parse cache: %v", err)
+	}
+	return &parseCache{dir: dir}, nil
+}
+
+// syntax tells how the content was parsed, the same bytes may be read as native or json syntax
+func (c *parseCache) key(syntax string, content []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(version + "\x00" + parseCacheFormat + "\x00" + syntax + "\x00"))
+	hash.Write(content)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (c *parseCache) entryPath(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".gob")
+}
+
+func (c *parseCache) Get(syntax string, content []byte) (*ast.File, bool) {
+	data, err := ioutil.ReadFile(c.entryPath(c.key(syntax, content)))
+	if nil != err {
+		atomic.AddInt64(&c.misses, 1)
+		return nil, false
+	}
+
+	var file ast.File
+	err = gob.NewDecoder(bytes.NewReader(data)).Decode(&file)
+	if nil != err {
+		log.Warningf("parse cache: broken entry is ignored: %v", err)
+		atomic.AddInt64(&c.misses, 1)
+		return nil, false
+	}
+	atomic.AddInt64(&c.hits, 1)
+	return &file, true
+}
+
+// errors are only logged, a missing entry means the file is parsed again next time
+func (c *parseCache) Put(syntax string, content []byte, file *ast.File) {
+	var buffer bytes.Buffer
+	err := gob.NewEncoder(&buffer).Encode(file)
+	if nil != err {
+		log.Warningf("parse cache: %v", err)
+		return
+	}
+
+	path := c.entryPath(c.key(syntax, content))
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if nil != err {
+		log.Warningf("parse cache: %v", err)
+		return
+	}
+
+	// concurrent runs must never see a partially written entry
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".entry")
+	if nil != err {
+		log.Warningf("parse cache: %v", err)
+		return
+	}
+	_, err = tmp.Write(buffer.Bytes())
+	if closeErr := tmp.Close(); nil == err {
+		err = closeErr
+	}
+	if nil == err {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if nil != err {
+		os.Remove(tmp.Name())
+		log.Warningf("parse cache: %v", err)
+	}
+}
+
+// cache directory must not be loaded as a module when it is inside the terraform root
+func (c *parseCache) IsCacheDir(dir string) bool {
+	dir, err := filepath.Abs(dir)
+	return nil == err && dir == c.dir
+}
+
+func (c *parseCache) Stats() (int64, int64) {
+	return atomic.LoadInt64(&c.hits), atomic.LoadInt64(&c.misses)
+}
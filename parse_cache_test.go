@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseCache(t *testing.T) {
+	Convey("Cached files must load into the same hierarchy", t, func() {
+		dir, err := ioutil.TempDir("", "hierarchy-cache")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		// created with the first entry only
+		cacheDir := filepath.Join(dir, "cache")
+		cache, err := newParseCache(cacheDir)
+		So(err, ShouldBeNil)
+		_, err = os.Stat(cacheDir)
+		So(os.IsNotExist(err), ShouldBeTrue)
+		moduleParseCache = cache
+		defer func() { moduleParseCache = nil }()
+
+		cold, err := json.Marshal(loadTestState("testdata/plan"))
+		So(err, ShouldBeNil)
+		hits, misses := cache.Stats()
+		So(hits, ShouldEqual, 0)
+		So(misses, ShouldBeGreaterThan, 0)
+		_, err = os.Stat(cacheDir)
+		So(err, ShouldBeNil)
+
+		warm, err := json.Marshal(loadTestState("testdata/plan"))
+		So(err, ShouldBeNil)
+		hits, _ = cache.Stats()
+		So(hits, ShouldEqual, misses)
+		So(string(warm), ShouldEqual, string(cold))
+	})
+	Convey("Entries of other tool versions must not be used", t, func() {
+		cache, err := newParseCache(hierarchyCacheDir)
+		So(err, ShouldBeNil)
+		key := cache.key("hcl", []byte(`variable "a" {}`))
+		So(cache.key("json", []byte(`variable "a" {}`)), ShouldNotEqual, key)
+
+		defer func(v string) { version = v }(version)
+		version = "1.2.3"
+		So(cache.key("hcl", []byte(`variable "a" {}`)), ShouldNotEqual, key)
+	})
+}
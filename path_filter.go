@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// which directories and files of the terraform root are loaded
+const hierarchyIgnoreFile = ".hierarchyignore"
+
+// never terraform modules
+var defaultExcludes = []string{".git", ".terraform", ".terragrunt-cache", hierarchyCacheDir}
+
+// repeated flag, values may also be separated by commas
+type globList []string
+
+func (l *globList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *globList) Set(value string) error {
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); "" != pattern {
+			*l = append(*l, pattern)
+		}
+	}
+	return nil
+}
+
+type pathFilter struct {
+	include []string
+	exclude []string
+}
+
+// command line globs, .hierarchyignore of the terraform root and default excludes
+func newPathFilter(terraformRoot string) *pathFilter {
+	filter := &pathFilter{include: includeGlobs}
+	filter.exclude = append(filter.exclude, defaultExcludes...)
+	filter.exclude = append(filter.exclude, excludeGlobs...)
+
+	ignored, err := readIgnoreFile(filepath.Join(terraformRoot, hierarchyIgnoreFile))
+	if nil != err {
+		log.Warningf("%s is ignored: %v", hierarchyIgnoreFile, err)
+	}
+	filter.exclude = append(filter.exclude, ignored...)
+	return filter
+}
+
+// one glob per line, empty lines and lines starting with # are skipped
+func readIgnoreFile(filePath string) ([]string, error) {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if nil != err {
+		return nil, err
+	}
+	defer file.Close()
+
+	var result []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if "" == line || strings.HasPrefix(line, "#") {
+			continue
+		}
+		result = append(result, line)
+	}
+	return result, scanner.Err()
+}
+
+// relPath is relative to the terraform root
+func (f *pathFilter) IsExcluded(relPath string) bool {
+	return matchAnyGlob(f.exclude, relPath)
+}
+
+// module directory is included when it or one of its parents matches
+func (f *pathFilter) IsIncluded(relDir string) bool {
+	if 0 == len(f.include) {
+		return true
+	}
+	for dir := filepath.ToSlash(relDir); ; dir = path.Dir(dir) {
+		if matchAnyGlob(f.include, dir) {
+			return true
+		}
+		if "." == dir || "/" == dir {
+			return false
+		}
+	}
+}
+
+func matchAnyGlob(patterns []string, relPath string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// patterns without a slash match any path element (like .gitignore), others match the whole path,
+// '**' matches any number of path elements
+func matchGlob(pattern string, relPath string) bool {
+	pattern = strings.TrimSuffix(filepath.ToSlash(pattern), "/")
+	relPath = filepath.ToSlash(relPath)
+
+	if !strings.Contains(pattern, "/") {
+		for _, element := range strings.Split(relPath, "/") {
+			if matched, _ := path.Match(pattern, element); matched {
+				return true
+			}
+		}
+		return false
+	}
+	return matchGlobElements(strings.Split(strings.TrimPrefix(pattern, "./"), "/"), strings.Split(relPath, "/"))
+}
+
+func matchGlobElements(pattern []string, elements []string) bool {
+	if 0 == len(pattern) {
+		return 0 == len(elements)
+	}
+	if "**" == pattern[0] {
+		for i := 0; i <= len(elements); i++ {
+			if matchGlobElements(pattern[1:], elements[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if 0 == len(elements) {
+		return false
+	}
+	matched, _ := path.Match(pattern[0], elements[0])
+	return matched && matchGlobElements(pattern[1:], elements[1:])
+}
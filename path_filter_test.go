@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPathFilter(t *testing.T) {
+	Convey("Globs must match path elements or whole paths", t, func() {
+		So(matchGlob(".terraform", "live/prod/.terraform"), ShouldBeTrue)
+		So(matchGlob("examples", "modules/vpc/examples"), ShouldBeTrue)
+		So(matchGlob("*.bak", "live/main.tf.bak"), ShouldBeTrue)
+		So(matchGlob("live/*", "live/prod"), ShouldBeTrue)
+		So(matchGlob("live/*", "live/prod/app"), ShouldBeFalse)
+		So(matchGlob("live/**/app", "live/prod/eu/app"), ShouldBeTrue)
+		So(matchGlob("live/**", "live"), ShouldBeTrue)
+		So(matchGlob("vendor/", "modules/vendor"), ShouldBeTrue)
+
+		So(isModuleFileName("main.tf"), ShouldBeTrue)
+		So(isModuleFileName("main.tf.json"), ShouldBeTrue)
+		So(isModuleFileName("main.tf.bak"), ShouldBeFalse)
+		So(isModuleFileName("terraform.tfstate"), ShouldBeFalse)
+	})
+
+	Convey("Discovery must skip excluded directories", t, func() {
+		dir, err := ioutil.TempDir("", "hierarchy-filter")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		for _, subdir := range []string{".terraform/modules/app", "live/prod", "modules/app/examples", "vendor/lib"} {
+			So(os.MkdirAll(filepath.Join(dir, subdir), 0755), ShouldBeNil)
+			So(ioutil.WriteFile(filepath.Join(dir, subdir, "main.tf"), []byte(""), 0644), ShouldBeNil)
+		}
+		So(ioutil.WriteFile(filepath.Join(dir, "live", "prod", "main.tf.bak"), []byte(""), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, hierarchyIgnoreFile), []byte("# vendored code\nvendor\n"), 0644), ShouldBeNil)
+
+		excludeGlobs = globList{"examples"}
+		defer func() { excludeGlobs = nil }()
+
+		moduleDirs, err := discoverModules(dir, ".")
+		So(err, ShouldBeNil)
+		var roots []string
+		for _, moduleDir := range moduleDirs {
+			roots = append(roots, moduleDir.Root)
+		}
+		So(roots, ShouldResemble, []string{".", "live", "live/prod", "modules", "modules/app"})
+		So(len(moduleDirs[2].Files), ShouldEqual, 1)
+
+		includeGlobs = globList{"live"}
+		defer func() { includeGlobs = nil }()
+
+		moduleDirs, err = discoverModules(dir, ".")
+		So(err, ShouldBeNil)
+		So(len(moduleDirs), ShouldEqual, 2)
+		So(moduleDirs[1].Root, ShouldEqual, "live/prod")
+	})
+}
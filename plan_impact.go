@@ -0,0 +1,338 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// terraform show -json plan representation (only the fields we need)
+type TerraformPlan struct {
+	ResourceChanges []PlanResourceChange `json:"resource_changes"`
+}
+
+type PlanResourceChange struct {
+	Address       string     `json:"address"`
+	ModuleAddress string     `json:"module_address"`
+	Mode          string     `json:"mode"`
+	Type          string     `json:"type"`
+	Name          string     `json:"name"`
+	Change        PlanChange `json:"change"`
+}
+
+type PlanChange struct {
+	Actions []string               `json:"actions"`
+	Before  map[string]interface{} `json:"before"`
+	After   map[string]interface{} `json:"after"`
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// impact report
+type ResourceChangeImpact struct {
+	Address   string   `form:"Address" json:"Address" xml:"Address"`
+	Actions   []string `form:"Actions" json:"Actions" xml:"Actions"`
+	Arguments []string `form:"Arguments" json:"Arguments" xml:"Arguments"`
+	Causes    []string `form:"Causes" json:"Causes" xml:"Causes"`
+}
+
+type ModuleImpact struct {
+	ModuleAddress string                 `form:"ModuleAddress" json:"ModuleAddress" xml:"ModuleAddress"`
+	Module        string                 `form:"Module" json:"Module" xml:"Module"`
+	Changes       []ResourceChangeImpact `form:"Changes" json:"Changes" xml:"Changes"`
+}
+
+type InputImpact struct {
+	Module  string   `form:"Module" json:"Module" xml:"Module"`
+	Input   string   `form:"Input" json:"Input" xml:"Input"`
+	Changes []string `form:"Changes" json:"Changes" xml:"Changes"`
+	Modules []string `form:"Modules" json:"Modules" xml:"Modules"`
+}
+
+type PlanImpactReport struct {
+	RootInputs []InputImpact  `form:"RootInputs" json:"RootInputs" xml:"RootInputs"`
+	Inputs     []InputImpact  `form:"Inputs" json:"Inputs" xml:"Inputs"`
+	Modules    []ModuleImpact `form:"Modules" json:"Modules" xml:"Modules"`
+}
+
+// variable as seen from the plan: module instance address and the input name
+type inputCause struct {
+	ModuleAddress string
+	Module        *Module
+	Input         string
+}
+
+func (c inputCause) String() string {
+	if "" == c.ModuleAddress {
+		return "var." + c.Input
+	}
+	return c.ModuleAddress + ".var." + c.Input
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// command
+func runPlanImpact(args []string) error {
+	flags := flag.NewFlagSet("plan-impact", flag.ExitOnError)
+	planPath := flags.String("plan", "", "plan in json format (terraform show -json plan.out)")
+	flags.Parse(args)
+
+	if "" == *planPath {
+		return fmt.Errorf("plan file must be set with -plan")
+	}
+
+	plan, err := loadPlan(*planPath)
+	if nil != err {
+		return err
+	}
+
+	state, _, err := loadState()
+	if nil != err {
+		return err
+	}
+
+	report := AnalyzePlanImpact(state, plan)
+	for _, impact := range report.RootInputs {
+		log.Infof("changing var.%s in root touches %d resources across %d modules", impact.Input, len(impact.Changes), len(impact.Modules))
+	}
+
+	jsonReport, err := json.Marshal(report)
+	if nil != err {
+		return err
+	}
+	return writeOutput(jsonReport)
+}
+
+func loadPlan(path string) (*TerraformPlan, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("plan loading: %v", err)
+	}
+
+	var plan TerraformPlan
+	err = json.Unmarshal(bytes, &plan)
+	if err != nil {
+		return nil, fmt.Errorf("plan loading: error unmarshalling plan: %v", err)
+	}
+	return &plan, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// analysis
+func AnalyzePlanImpact(state *HierarchyState, plan *TerraformPlan) *PlanImpactReport {
+	root := state.allModulesMap["."]
+
+	modules := make(map[string]*ModuleImpact)
+	inputs := make(map[string]*InputImpact)
+	var moduleOrder, inputOrder []string
+
+	for _, change := range plan.ResourceChanges {
+		if "data" == change.Mode || isNoopChange(change.Change.Actions) {
+			continue
+		}
+
+		chain, err := resolveModuleAddress(state, root, change.ModuleAddress)
+		if nil != err {
+			log.Warningf("plan impact: resource %s: %v", change.Address, err)
+			continue
+		}
+
+		arguments := changedArguments(change.Change)
+		causes := findChangeCauses(state, chain, change.Type, change.Name, arguments)
+
+		impact := ResourceChangeImpact{Address: change.Address, Actions: change.Change.Actions, Arguments: arguments}
+		for _, cause := range causes {
+			impact.Causes = append(impact.Causes, cause.String())
+
+			key := cause.String()
+			inputImpact, found := inputs[key]
+			if !found {
+				inputImpact = &InputImpact{Module: cause.Module.Name, Input: cause.Input}
+				if "" != cause.ModuleAddress {
+					inputImpact.Module = cause.ModuleAddress
+				}
+				inputs[key] = inputImpact
+				inputOrder = append(inputOrder, key)
+			}
+			if !Include(inputImpact.Changes, change.Address) {
+				inputImpact.Changes = append(inputImpact.Changes, change.Address)
+			}
+			if !Include(inputImpact.Modules, moduleAddressOrRoot(change.ModuleAddress)) {
+				inputImpact.Modules = append(inputImpact.Modules, moduleAddressOrRoot(change.ModuleAddress))
+			}
+		}
+
+		moduleAddress := moduleAddressOrRoot(change.ModuleAddress)
+		moduleImpact, found := modules[moduleAddress]
+		if !found {
+			moduleImpact = &ModuleImpact{ModuleAddress: moduleAddress, Module: chain[len(chain)-1].Module.Name}
+			modules[moduleAddress] = moduleImpact
+			moduleOrder = append(moduleOrder, moduleAddress)
+		}
+		moduleImpact.Changes = append(moduleImpact.Changes, impact)
+	}
+
+	sort.Strings(moduleOrder)
+	sort.Strings(inputOrder)
+
+	report := &PlanImpactReport{}
+	for _, key := range moduleOrder {
+		report.Modules = append(report.Modules, *modules[key])
+	}
+	for _, key := range inputOrder {
+		impact := *inputs[key]
+		sort.Strings(impact.Changes)
+		sort.Strings(impact.Modules)
+		report.Inputs = append(report.Inputs, impact)
+		if "." == impact.Module {
+			report.RootInputs = append(report.RootInputs, impact)
+		}
+	}
+	return report
+}
+
+func isNoopChange(actions []string) bool {
+	return All(actions, func(action string) bool { return "no-op" == action || "read" == action })
+}
+
+func moduleAddressOrRoot(moduleAddress string) string {
+	if "" == moduleAddress {
+		return "."
+	}
+	return moduleAddress
+}
+
+// instance chain from the root module down to the module containing the resource
+type moduleStep struct {
+	Address      string
+	InstanceName string
+	Module       *Module
+}
+
+// resolves "module.a[0].module.b" into root -> a -> b
+func resolveModuleAddress(state *HierarchyState, root *Module, moduleAddress string) ([]moduleStep, error) {
+	if nil == root {
+		return nil, fmt.Errorf("root module is not loaded")
+	}
+
+	chain := []moduleStep{{Module: root}}
+	if "" == moduleAddress {
+		return chain, nil
+	}
+
+	parts := strings.Split(moduleAddress, ".")
+	if len(parts)%2 != 0 {
+		return nil, fmt.Errorf("malformed module address '%s'", moduleAddress)
+	}
+
+	current := root
+	address := ""
+	for i := 0; i < len(parts); i += 2 {
+		if "module" != parts[i] {
+			return nil, fmt.Errorf("malformed module address '%s'", moduleAddress)
+		}
+		instanceName := parts[i+1]
+		if index := strings.Index(instanceName, "["); index >= 0 {
+			instanceName = instanceName[:index]
+		}
+
+		instance := current.FindModuleInstance(instanceName)
+		if nil == instance || nil == instance.Instance {
+			return nil, fmt.Errorf("module instance '%s' is not found in module '%s'", instanceName, current.Name)
+		}
+
+		if "" != address {
+			address += "."
+		}
+		address += "module." + instanceName
+
+		current = state.allModulesMap[instance.ModulePath]
+		if nil == current {
+			current = instance.Instance
+		}
+		chain = append(chain, moduleStep{Address: address, InstanceName: instanceName, Module: current})
+	}
+	return chain, nil
+}
+
+// top level arguments which differ between before and after, everything for create/delete
+func changedArguments(change PlanChange) []string {
+	keys := make(map[string]bool)
+	for key := range change.Before {
+		keys[key] = true
+	}
+	for key := range change.After {
+		keys[key] = true
+	}
+
+	update := len(change.Actions) == 1 && "update" == change.Actions[0]
+
+	result := make([]string, 0, len(keys))
+	for key := range keys {
+		if !update || !reflect.DeepEqual(change.Before[key], change.After[key]) {
+			result = append(result, key)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// walks AsArgument edges of the resource module and then AsModuleInput edges up to the root
+func findChangeCauses(state *HierarchyState, chain []moduleStep, resourceType string, resourceName string, arguments []string) []inputCause {
+	last := chain[len(chain)-1]
+
+	var current []inputCause
+	for _, input := range last.Module.Inputs {
+		for _, usage := range input.AsArgument {
+			if usagePathsMatch(usage.UsagePath, func(path []string) bool {
+				return len(path) >= 3 && resourceType == path[0] && resourceName == path[1] && Include(arguments, unquote(path[2]))
+			}) {
+				current = append(current, inputCause{ModuleAddress: last.Address, Module: last.Module, Input: input.Name})
+				break
+			}
+		}
+	}
+
+	result := append([]inputCause{}, current...)
+	for level := len(chain) - 1; level > 0 && len(current) > 0; level-- {
+		child := chain[level]
+		parent := chain[level-1]
+
+		var next []inputCause
+		for _, input := range parent.Module.Inputs {
+			for _, usage := range input.AsModuleInput {
+				if usagePathsMatch(usage.UsagePath, func(path []string) bool {
+					if len(path) < 2 || child.InstanceName != path[0] {
+						return false
+					}
+					for _, cause := range current {
+						if cause.Input == unquote(path[1]) {
+							return true
+						}
+					}
+					return false
+				}) {
+					next = append(next, inputCause{ModuleAddress: parent.Address, Module: parent.Module, Input: input.Name})
+					break
+				}
+			}
+		}
+		result = append(result, next...)
+		current = next
+	}
+	return result
+}
+
+func usagePathsMatch(usagePaths [][]string, f func([]string) bool) bool {
+	for _, path := range usagePaths {
+		if f(path) {
+			return true
+		}
+	}
+	return false
+}
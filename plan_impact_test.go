@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func loadTestState(dir string) *HierarchyState {
+	*rootDir = dir
+	state := NewHierarchyState()
+	loadModule(dir, ".", nil, state)
+	return state
+}
+
+func TestPlanImpact(t *testing.T) {
+	Convey("Plan changes must be traced back to root inputs", t, func() {
+		state := loadTestState("testdata/plan")
+		plan, err := loadPlan("testdata/plan/plan.json")
+		So(err, ShouldBeNil)
+
+		report := AnalyzePlanImpact(state, plan)
+		So(len(report.Modules), ShouldEqual, 1)
+		So(report.Modules[0].ModuleAddress, ShouldEqual, "module.app")
+		So(report.Modules[0].Module, ShouldEqual, "modules.app")
+		So(len(report.Modules[0].Changes), ShouldEqual, 1)
+		So(report.Modules[0].Changes[0].Arguments, ShouldResemble, []string{"ami"})
+		So(report.Modules[0].Changes[0].Causes, ShouldResemble, []string{"module.app.var.image", "var.ami"})
+
+		So(len(report.RootInputs), ShouldEqual, 1)
+		So(report.RootInputs[0].Input, ShouldEqual, "ami")
+		So(report.RootInputs[0].Changes, ShouldResemble, []string{"module.app.aws_instance.web[0]"})
+		So(report.RootInputs[0].Modules, ShouldResemble, []string{"module.app"})
+	})
+}
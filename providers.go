@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// provider configuration every resource of every root ends up with
+type ResourceProvider struct {
+	Resource string `form:"Resource" json:"Resource" xml:"Resource"`
+	Module   string `form:"Module" json:"Module" xml:"Module"`
+	// provider address used in the resource module
+	Provider string `form:"Provider" json:"Provider" xml:"Provider"`
+	// module and provider address at every module call the provider was passed through
+	Chain         []string        `form:"Chain" json:"Chain" xml:"Chain"`
+	ConfiguredIn  string          `form:"ConfiguredIn" json:"ConfiguredIn,omitempty" xml:"ConfiguredIn"`
+	Configuration *ModuleProvider `form:"Configuration" json:"Configuration,omitempty" xml:"Configuration"`
+	Error         string          `form:"Error" json:"Error,omitempty" xml:"Error"`
+}
+
+// module on the way from a root, with the call that reached it
+type providerScope struct {
+	Module   *Module
+	Instance *ModuleInstance
+	Address  string
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// command
+func runProviders(args []string) error {
+	flags := flag.NewFlagSet("providers", flag.ExitOnError)
+	resource := flags.String("resource", "", "print only resources with the address containing the string")
+	flags.Parse(args)
+
+	state, _, err := loadState()
+	if nil != err {
+		return err
+	}
+
+	var report []ResourceProvider
+	for _, usage := range ResolveResourceProviders(state) {
+		if strings.Contains(usage.Resource, *resource) {
+			report = append(report, usage)
+		}
+	}
+
+	jsonReport, err := json.Marshal(report)
+	if nil != err {
+		return err
+	}
+	return writeOutput(jsonReport)
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// resolution
+func ResolveResourceProviders(state *HierarchyState) []ResourceProvider {
+	var result []ResourceProvider
+
+	for _, module := range rootModules(state) {
+		result = append(result, resolveScopeProviders([]providerScope{{Module: module}})...)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Resource < result[j].Resource })
+	return result
+}
+
+func resolveScopeProviders(chain []providerScope) []ResourceProvider {
+	var result []ResourceProvider
+	current := chain[len(chain)-1]
+
+	for _, resource := range current.Module.Resources {
+		usage := resolveResourceProvider(chain, resource)
+		usage.Resource = current.Address + resource.Type + "." + resource.Name
+		result = append(result, usage)
+	}
+
+	for _, instance := range current.Module.ModuleInstances {
+		if nil == instance.Instance || isModuleInChain(chain, instance.Instance) {
+			continue
+		}
+		scope := providerScope{Module: instance.Instance, Instance: instance, Address: current.Address + "module." + instance.InstanceName + "."}
+		result = append(result, resolveScopeProviders(append(chain[:len(chain):len(chain)], scope))...)
+	}
+	return result
+}
+
+func isModuleInChain(chain []providerScope, module *Module) bool {
+	for _, scope := range chain {
+		if scope.Module == module {
+			return true
+		}
+	}
+	return false
+}
+
+// provider configurations are looked up from the resource module towards the root,
+// default providers are inherited implicitly unless the module call passes providers explicitly
+func resolveResourceProvider(chain []providerScope, resource ModuleResource) ResourceProvider {
+	address := resource.Provider
+	if "" == address {
+		address = defaultProviderName(resource.Type)
+	}
+	usage := ResourceProvider{Module: chain[len(chain)-1].Module.Name, Provider: address}
+
+	for level := len(chain) - 1; level >= 0; level-- {
+		scope := chain[level]
+		usage.Chain = append(usage.Chain, scope.Module.Name+": "+address)
+
+		if provider, found := scope.Module.FindProvider(address); found {
+			usage.ConfiguredIn = scope.Module.Name
+			usage.Configuration = &provider
+			return usage
+		}
+		if nil == scope.Instance {
+			break
+		}
+
+		passed, found := scope.Instance.Providers[address]
+		switch {
+		case found:
+			address = passed
+		case 0 != len(scope.Instance.Providers):
+			usage.Error = fmt.Sprintf("provider %s is not passed to module call %s", address, scope.Instance.InstanceName)
+			return usage
+		case strings.Contains(address, "."):
+			usage.Error = fmt.Sprintf("aliased provider %s must be passed to module call %s explicitly", address, scope.Instance.InstanceName)
+			return usage
+		}
+	}
+
+	if strings.Contains(address, ".") {
+		usage.Error = fmt.Sprintf("provider %s is not configured", address)
+	}
+	// default providers without configuration are configured from the environment
+	return usage
+}
+
+// aws_instance -> aws
+func defaultProviderName(resourceType string) string {
+	return strings.SplitN(resourceType, "_", 2)[0]
+}
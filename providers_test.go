@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestResolveResourceProviders(t *testing.T) {
+	Convey("Providers must be traced through module calls", t, func() {
+		state := loadTestState("testdata/providers")
+
+		root := state.allModulesMap["."]
+		So(len(root.Providers), ShouldEqual, 2)
+		So(root.Providers[1].Address(), ShouldEqual, "aws.us")
+		So(root.Providers[1].Arguments, ShouldResemble, map[string]string{"region": "us-east-1"})
+		So(root.FindModuleInstance("app_us").Providers, ShouldResemble, map[string]string{"aws": "aws.us"})
+
+		regions := make(map[string]string)
+		for _, usage := range ResolveResourceProviders(state) {
+			So(usage.Error, ShouldEqual, "")
+			regions[usage.Resource] = usage.Configuration.Arguments["region"]
+		}
+		So(regions, ShouldResemble, map[string]string{
+			"module.app.aws_instance.web":                     "eu-west-1",
+			"module.app.module.db.aws_db_instance.main":       "eu-west-1",
+			"module.app.module.db.aws_db_instance.replica":    "ap-south-1",
+			"module.app_us.aws_instance.web":                  "us-east-1",
+			"module.app_us.module.db.aws_db_instance.main":    "us-east-1",
+			"module.app_us.module.db.aws_db_instance.replica": "ap-south-1",
+		})
+	})
+}
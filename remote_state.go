@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// roots reading each other through data "terraform_remote_state"
+type RemoteStateLink struct {
+	// module declaring the remote state and the remote state name
+	Module      string `form:"Module" json:"Module" xml:"Module"`
+	RemoteState string `form:"RemoteState" json:"RemoteState" xml:"RemoteState"`
+	// root writing the state, empty when no loaded root matches the backend config
+	SourceRoot string `form:"SourceRoot" json:"SourceRoot" xml:"SourceRoot"`
+	// several roots may write the state when the config does not name it completely (interpolated or missing keys)
+	Ambiguous  bool                `form:"Ambiguous" json:"Ambiguous,omitempty" xml:"Ambiguous"`
+	Candidates []string            `form:"Candidates" json:"Candidates,omitempty" xml:"Candidates"`
+	Outputs    []RemoteStateOutput `form:"Outputs" json:"Outputs" xml:"Outputs"`
+}
+
+type RemoteStateOutput struct {
+	Output string             `form:"Output" json:"Output" xml:"Output"`
+	Found  bool               `form:"Found" json:"Found" xml:"Found"`
+	Usages []RemoteStateUsage `form:"Usages" json:"Usages" xml:"Usages"`
+}
+
+// backend of a root, roots without backend block keep their state in terraform.tfstate next to them
+type backendIdentity struct {
+	Backend string
+	Config  map[string]string
+}
+
+type backendMatch int
+
+const (
+	backendMismatch backendMatch = iota
+	// the known keys are equal, but some keys naming the state are unknown
+	backendPartial
+	backendMatches
+)
+
+// config keys naming the state, other backends are named by every key of the reader config
+var backendStateKeys = map[string][]string{
+	"local":   {"path"},
+	"s3":      {"bucket", "key"},
+	"gcs":     {"bucket", "prefix"},
+	"azurerm": {"storage_account_name", "container_name", "key"},
+	"consul":  {"path"},
+	"remote":  {"organization", "workspaces.name"},
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// command
+func runRemoteStates(args []string) error {
+	flags := flag.NewFlagSet("remote-states", flag.ExitOnError)
+	flags.Parse(args)
+
+	state, _, err := loadState()
+	if nil != err {
+		return err
+	}
+
+	jsonReport, err := json.Marshal(LinkRemoteStates(state))
+	if nil != err {
+		return err
+	}
+	return writeOutput(jsonReport)
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// linking
+func LinkRemoteStates(state *HierarchyState) []RemoteStateLink {
+	var links []RemoteStateLink
+
+	roots := rootModules(state)
+	for _, module := range state.AllModules {
+		for _, remoteState := range module.RemoteStates {
+			link := RemoteStateLink{Module: module.Name, RemoteState: remoteState.Name}
+
+			var source *Module
+			if remoteState.IsLoaded {
+				var candidates []*Module
+				source, candidates = findStateRoot(roots, module, remoteState)
+				for _, candidate := range candidates {
+					link.Candidates = append(link.Candidates, candidate.Name)
+				}
+				link.Ambiguous = 0 != len(candidates)
+			}
+			if nil != source {
+				link.SourceRoot = source.Name
+			}
+
+			outputs := make(map[string]int)
+			for _, usage := range remoteState.Usages {
+				index, found := outputs[usage.Output]
+				if !found {
+					index = len(link.Outputs)
+					outputs[usage.Output] = index
+					link.Outputs = append(link.Outputs, RemoteStateOutput{Output: usage.Output, Found: nil != source && hasOutput(source, usage.Output)})
+				}
+				link.Outputs[index].Usages = append(link.Outputs[index].Usages, usage)
+			}
+			links = append(links, link)
+		}
+	}
+
+	sort.SliceStable(links, func(i, j int) bool {
+		if links[i].Module != links[j].Module {
+			return links[i].Module < links[j].Module
+		}
+		return links[i].RemoteState < links[j].RemoteState
+	})
+	return links
+}
+
+// the only root writing the state, or roots that may write it when there is no single one
+func findStateRoot(roots []*Module, reader *Module, remoteState *ModuleRemoteState) (*Module, []*Module) {
+	wanted := backendIdentity{Backend: remoteState.Backend, Config: remoteState.Config}
+	if "local" == wanted.Backend {
+		wanted.Config = resolveLocalStatePath(reader, wanted.Config)
+	}
+
+	var matches, partial []*Module
+	for _, root := range roots {
+		if root == reader {
+			continue
+		}
+		switch wanted.Match(rootBackend(root)) {
+		case backendMatches:
+			matches = append(matches, root)
+		case backendPartial:
+			partial = append(partial, root)
+		}
+	}
+
+	switch {
+	case 1 == len(matches):
+		return matches[0], nil
+	case 0 != len(matches):
+		return nil, matches
+	default:
+		return nil, partial
+	}
+}
+
+func rootBackend(root *Module) backendIdentity {
+	if nil == root.Terraform || "" == root.Terraform.Backend {
+		return backendIdentity{Backend: "local", Config: resolveLocalStatePath(root, nil)}
+	}
+
+	identity := backendIdentity{Backend: root.Terraform.Backend, Config: root.Terraform.BackendConfig}
+	if "local" == identity.Backend {
+		identity.Config = resolveLocalStatePath(root, identity.Config)
+	}
+	return identity
+}
+
+// relative local state paths are resolved against the module directory, the working directory for roots
+func resolveLocalStatePath(module *Module, config map[string]string) map[string]string {
+	path := config["path"]
+	if "" == path {
+		path = "terraform.tfstate"
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(module.Path, path)
+	}
+	return map[string]string{"path": filepath.Clean(path)}
+}
+
+// keys set on both sides must be equal, every key naming the state must be known to match
+func (b backendIdentity) Match(other backendIdentity) backendMatch {
+	if b.Backend != other.Backend {
+		return backendMismatch
+	}
+	for key, value := range b.Config {
+		if otherValue, found := other.Config[key]; found && !isInterpolated(value) && otherValue != value {
+			return backendMismatch
+		}
+	}
+
+	keys, found := backendStateKeys[b.Backend]
+	if !found {
+		keys = make([]string, 0, len(b.Config))
+		for key := range b.Config {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		value, found := b.Config[key]
+		if !found || isInterpolated(value) {
+			return backendPartial
+		}
+		if otherValue, found := other.Config[key]; !found || otherValue != value {
+			return backendMismatch
+		}
+	}
+	if 0 == len(keys) {
+		return backendPartial
+	}
+	return backendMatches
+}
+
+// values known only when terraform runs
+func isInterpolated(value string) bool {
+	return strings.Contains(value, "${")
+}
+
+func hasOutput(module *Module, name string) bool {
+	for _, output := range module.Outputs {
+		if output.Name == name && output.IsLoaded {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLinkRemoteStates(t *testing.T) {
+	Convey("Remote states must be linked to the roots writing them", t, func() {
+		state := loadTestState("testdata/remote_state")
+
+		links := LinkRemoteStates(state)
+		So(len(links), ShouldEqual, 3)
+
+		So(links[0].RemoteState, ShouldEqual, "db")
+		So(links[0].SourceRoot, ShouldEqual, "db")
+		So(links[0].Outputs[0].Output, ShouldEqual, "endpoint")
+		So(links[0].Outputs[0].Found, ShouldBeTrue)
+
+		So(links[1].RemoteState, ShouldEqual, "network")
+		So(links[1].SourceRoot, ShouldEqual, "network")
+		So(len(links[1].Outputs), ShouldEqual, 2)
+		So(links[1].Outputs[0].Usages[0].UsagePath, ShouldResemble, []string{"aws_security_group", "app", "vpc_id"})
+		So(links[1].Outputs[1].Output, ShouldEqual, "subnet_id")
+		So(links[1].Outputs[1].Found, ShouldBeFalse)
+
+		// the key is only known when terraform runs, both roots in the bucket may write the state
+		So(links[2].RemoteState, ShouldEqual, "shared")
+		So(links[2].SourceRoot, ShouldEqual, "")
+		So(links[2].Ambiguous, ShouldBeTrue)
+		So(links[2].Candidates, ShouldResemble, []string{"dns", "network"})
+		So(links[2].Outputs[0].Found, ShouldBeFalse)
+
+		graph := NewFlowGraph(state)
+		edges := graph.Downstream(outputNodeID("network", "vpc_id"))
+		So(len(edges), ShouldEqual, 1)
+		So(edges[0].To, ShouldEqual, resourceNodeID("app", "aws_security_group", "app"))
+		So(edges[0].Kind, ShouldEqual, "FromRemoteState")
+	})
+	Convey("Every key naming the state must match", t, func() {
+		s3 := func(config map[string]string) backendIdentity { return backendIdentity{Backend: "s3", Config: config} }
+		root := s3(map[string]string{"bucket": "infra-state", "key": "network/terraform.tfstate", "region": "eu-west-1"})
+
+		cases := []struct {
+			reader backendIdentity
+			match  backendMatch
+		}{
+			{reader: s3(map[string]string{"bucket": "infra-state", "key": "network/terraform.tfstate"}), match: backendMatches},
+			{reader: s3(map[string]string{"bucket": "infra-state", "key": "network/terraform.tfstate", "region": "us-east-1"}), match: backendMismatch},
+			{reader: s3(map[string]string{"bucket": "infra-state", "key": "dns/terraform.tfstate"}), match: backendMismatch},
+			{reader: s3(map[string]string{"bucket": "infra-state"}), match: backendPartial},
+			{reader: s3(map[string]string{"bucket": "infra-state", "key": "${var.key}"}), match: backendPartial},
+			{reader: s3(map[string]string{"bucket": "other-state"}), match: backendMismatch},
+			{reader: backendIdentity{Backend: "gcs", Config: map[string]string{"bucket": "infra-state"}}, match: backendMismatch},
+		}
+		for _, c := range cases {
+			So(c.reader.Match(root), ShouldEqual, c.match)
+		}
+
+		remote := backendIdentity{Backend: "remote", Config: map[string]string{"organization": "acme", "workspaces.name": "network"}}
+		So(remote.Match(remote), ShouldEqual, backendMatches)
+		So(backendIdentity{Backend: "remote", Config: map[string]string{"organization": "acme"}}.Match(remote), ShouldEqual, backendPartial)
+	})
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// lint findings as SARIF 2.1.0 for code scanning annotations
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	// uris of results are relative to the terraform root
+	sarifRootBaseID = "%SRCROOT%"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                        `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult                    `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version"`
+	Rules   []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine,omitempty"`
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// conversion
+func WriteSARIF(findings []LintFinding, rules []LintRule, config *lintConfig, terraformRoot string) ([]byte, error) {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "terraform-hierarchy", Version: version}},
+		Results: []sarifResult{},
+	}
+	if absRoot, err := filepath.Abs(terraformRoot); nil == err {
+		run.OriginalURIBaseIDs = map[string]sarifArtifactLocation{sarifRootBaseID: {URI: "file://" + filepath.ToSlash(absRoot) + "/"}}
+	}
+
+	ruleIndexes := make(map[string]int)
+	for _, rule := range rules {
+		ruleIndexes[rule.ID] = len(run.Tool.Driver.Rules)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:                   rule.ID,
+			ShortDescription:     sarifMessage{Text: rule.Description},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevel(config.Severity(rule))},
+		})
+	}
+
+	for _, finding := range findings {
+		result := sarifResult{RuleID: finding.Rule, RuleIndex: ruleIndexes[finding.Rule], Level: sarifLevel(finding.Severity), Message: sarifMessage{Text: finding.Message}}
+		if "" != finding.Pos.Filename {
+			location := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: sarifURI(terraformRoot, finding.Pos.Filename), URIBaseID: sarifRootBaseID}}
+			if finding.Pos.Line > 0 {
+				location.Region = &sarifRegion{StartLine: finding.Pos.Line, EndLine: finding.Pos.EndLine}
+			}
+			result.Locations = []sarifLocation{{PhysicalLocation: location}}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	return json.Marshal(sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []sarifRun{run}})
+}
+
+func sarifLevel(severity string) string {
+	switch severity {
+	case severityError:
+		return "error"
+	case severityWarning:
+		return "warning"
+	case severityOff:
+		return "none"
+	default:
+		return "note"
+	}
+}
+
+// file paths of findings start with the terraform root
+func sarifURI(terraformRoot string, filePath string) string {
+	if relPath, err := filepath.Rel(terraformRoot, filePath); nil == err {
+		return filepath.ToSlash(relPath)
+	}
+	return filepath.ToSlash(filePath)
+}
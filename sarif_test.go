@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	Convey("Lint findings must map to sarif rules, files and regions", t, func() {
+		state := loadTestState("testdata/lint")
+		config, err := readLintConfig("testdata/lint/" + hierarchyLintFile)
+		So(err, ShouldBeNil)
+		findings := RunLint(state, nil, config)
+
+		report, err := WriteSARIF(findings, lintRules, config, "testdata/lint")
+		So(err, ShouldBeNil)
+
+		var log sarifLog
+		So(json.Unmarshal(report, &log), ShouldBeNil)
+		So(log.Version, ShouldEqual, "2.1.0")
+		So(len(log.Runs), ShouldEqual, 1)
+
+		run := log.Runs[0]
+		So(len(run.Tool.Driver.Rules), ShouldEqual, len(lintRules))
+		So(len(run.Results), ShouldEqual, len(findings))
+
+		// broken/main.tf goes first
+		invalid := run.Results[0]
+		So(invalid.RuleID, ShouldEqual, "invalid-file")
+		So(run.Tool.Driver.Rules[invalid.RuleIndex].ID, ShouldEqual, "invalid-file")
+		So(invalid.Locations[0].PhysicalLocation.ArtifactLocation.URI, ShouldEqual, "broken/main.tf")
+		So(invalid.Locations[0].PhysicalLocation.Region.StartLine, ShouldBeGreaterThan, 1)
+
+		unused := run.Results[1]
+		So(unused.RuleID, ShouldEqual, "unused-variable")
+		So(unused.Level, ShouldEqual, "warning")
+		So(unused.Locations[0].PhysicalLocation.ArtifactLocation.URI, ShouldEqual, "main.tf")
+		So(unused.Locations[0].PhysicalLocation.ArtifactLocation.URIBaseID, ShouldEqual, "%SRCROOT%")
+		So(*unused.Locations[0].PhysicalLocation.Region, ShouldResemble, sarifRegion{StartLine: 10, EndLine: 12})
+		So(sarifLevel(severityInfo), ShouldEqual, "note")
+	})
+}
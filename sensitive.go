@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// sensitive values reaching places where they end up in plain text
+type SensitiveFlow struct {
+	// input node of a sensitive variable or resource node with the sensitive attribute
+	Source    string `form:"Source" json:"Source" xml:"Source"`
+	Attribute string `form:"Attribute" json:"Attribute,omitempty" xml:"Attribute"`
+	// output node not marked sensitive or resource node with the argument the value ends up in
+	Sink     string    `form:"Sink" json:"Sink" xml:"Sink"`
+	SinkKind string    `form:"SinkKind" json:"SinkKind" xml:"SinkKind"`
+	Argument string    `form:"Argument" json:"Argument,omitempty" xml:"Argument"`
+	Pos      SourcePos `form:"Pos" json:"Pos" xml:"Pos"`
+	// edges from the source to the sink
+	Path []FlowEdge `form:"Path" json:"Path" xml:"Path"`
+}
+
+// arguments shown in consoles, tags and instance metadata
+var defaultSensitiveSinks = []string{"description", "name", "name_prefix", "tags", "user_data", "user_data_base64"}
+
+// attributes treated as sensitive when the resource description does not say so
+var sensitiveAttributeRegexp = regexp.MustCompile("(^|_)(password|secret|private_key|token)($|_)")
+
+// value flowing through the graph, for resource nodes the argument it was passed to
+type taintedValue struct {
+	Node     string
+	Argument string
+	Path     []FlowEdge
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// command
+func runSensitive(args []string) error {
+	flags := flag.NewFlagSet("sensitive", flag.ExitOnError)
+	sinks := flags.String("sinks", strings.Join(defaultSensitiveSinks, ","), "comma separated resource arguments sensitive values must not reach")
+	fail := flags.Bool("fail", false, "fail when any sensitive flow is found")
+	flags.Parse(args)
+
+	state, awsResources, err := loadState()
+	if nil != err {
+		return err
+	}
+
+	flows := FindSensitiveFlows(state, awsResources, strings.Split(*sinks, ","))
+	jsonReport, err := json.Marshal(flows)
+	if nil != err {
+		return err
+	}
+	err = writeOutput(jsonReport)
+	if nil != err {
+		return err
+	}
+
+	if *fail && 0 != len(flows) {
+		return fmt.Errorf("%d sensitive flows found", len(flows))
+	}
+	return nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// analysis
+func FindSensitiveFlows(state *HierarchyState, awsResources []Resource, sinks []string) []SensitiveFlow {
+	graph := NewFlowGraph(state)
+	var flows []SensitiveFlow
+
+	for _, module := range state.AllModules {
+		for _, input := range module.Inputs {
+			if input.Sensitive {
+				source := inputNodeID(module.Name, input.Name)
+				flows = append(flows, traceSensitiveValue(state, graph, sinks, source, "", []taintedValue{{Node: source}})...)
+			}
+		}
+	}
+
+	// edges reading a sensitive attribute start at the resource
+	for _, node := range graph.Nodes {
+		if "resource" != node.Kind {
+			continue
+		}
+		resourceType := strings.SplitN(node.Name, ".", 2)[0]
+		var attributes []string
+		for _, i := range graph.outgoing[node.ID] {
+			edge := graph.Edges[i]
+			if isSensitiveAttribute(resourceType, edge.Label, awsResources) && !Include(attributes, edge.Label) {
+				attributes = append(attributes, edge.Label)
+			}
+		}
+		for _, attribute := range attributes {
+			start := []taintedValue{{Node: node.ID, Argument: attribute}}
+			flows = append(flows, traceSensitiveValue(state, graph, sinks, node.ID, attribute, start)...)
+		}
+	}
+
+	sort.SliceStable(flows, func(i, j int) bool {
+		if flows[i].Source != flows[j].Source {
+			return flows[i].Source < flows[j].Source
+		}
+		return flows[i].Sink < flows[j].Sink
+	})
+	return flows
+}
+
+// breadth first, so the shortest path to every sink is reported
+func traceSensitiveValue(state *HierarchyState, graph *FlowGraph, sinks []string, source string, attribute string, queue []taintedValue) []SensitiveFlow {
+	var flows []SensitiveFlow
+	visited := make(map[string]bool)
+	reported := make(map[string]bool)
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		key := current.Node + "\x00" + current.Argument
+		if visited[key] {
+			continue
+		}
+		visited[key] = true
+
+		node, _ := graph.Node(current.Node)
+		for _, i := range graph.outgoing[current.Node] {
+			edge := graph.Edges[i]
+			// an argument of a resource comes back as the attribute of the same name only
+			if "resource" == node.Kind && edge.Label != current.Argument {
+				continue
+			}
+			next := taintedValue{Node: edge.To, Path: append(current.Path[:len(current.Path):len(current.Path)], edge)}
+			target, _ := graph.Node(edge.To)
+
+			switch target.Kind {
+			case "resource":
+				next.Argument = edge.Label
+				if Include(sinks, edge.Label) && !reported[edge.To+"\x00"+edge.Label] {
+					reported[edge.To+"\x00"+edge.Label] = true
+					flows = append(flows, SensitiveFlow{Source: source, Attribute: attribute, Sink: edge.To, SinkKind: "argument", Argument: edge.Label, Pos: target.Pos, Path: next.Path})
+				}
+			case "output":
+				if !isSensitiveOutput(state, target) && !reported[edge.To] {
+					reported[edge.To] = true
+					flows = append(flows, SensitiveFlow{Source: source, Attribute: attribute, Sink: edge.To, SinkKind: "output", Pos: target.Pos, Path: next.Path})
+				}
+			}
+			queue = append(queue, next)
+		}
+	}
+	return flows
+}
+
+func isSensitiveOutput(state *HierarchyState, node FlowNode) bool {
+	module, found := state.allModulesMap[node.Module]
+	if !found {
+		return false
+	}
+	for _, output := range module.Outputs {
+		if output.Name == node.Name {
+			return output.Sensitive
+		}
+	}
+	return false
+}
+
+func isSensitiveAttribute(resourceType string, name string, awsResources []Resource) bool {
+	if attribute := getAttributeByName(resourceType, name, awsResources); nil != attribute && attribute.Sensitive {
+		return true
+	}
+	return sensitiveAttributeRegexp.MatchString(name)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFindSensitiveFlows(t *testing.T) {
+	Convey("Sensitive values must be traced to plain text outputs and arguments", t, func() {
+		state := loadTestState("testdata/sensitive")
+		So(state.allInputsMap[".db_password"].Sensitive, ShouldBeTrue)
+
+		flows := FindSensitiveFlows(state, nil, defaultSensitiveSinks)
+		So(len(flows), ShouldEqual, 5)
+
+		So(flows[0].Source, ShouldEqual, inputNodeID(".", "db_password"))
+		So(flows[0].Sink, ShouldEqual, outputNodeID("modules.db", "password"))
+		So(flows[0].SinkKind, ShouldEqual, "output")
+		So(len(flows[0].Path), ShouldEqual, 3)
+
+		So(flows[1].Sink, ShouldEqual, resourceNodeID(".", "aws_instance", "app"))
+		So(flows[1].Argument, ShouldEqual, "user_data")
+		So(flows[1].Path[0].To, ShouldEqual, localNodeID(".", "connection"))
+
+		// values nested in tag blocks and maps reach the tags argument
+		So(flows[2].Sink, ShouldEqual, resourceNodeID(".", "aws_s3_bucket", "backup"))
+		So(flows[2].Argument, ShouldEqual, "tags")
+		So(flows[3].Sink, ShouldEqual, resourceNodeID(".", "aws_sqs_queue", "jobs"))
+		So(flows[3].Argument, ShouldEqual, "tags")
+
+		// the attribute is sensitive by name, the sensitive root output is not reported
+		So(flows[4].Source, ShouldEqual, resourceNodeID("modules.db", "aws_db_instance", "main"))
+		So(flows[4].Attribute, ShouldEqual, "password")
+		So(flows[4].Sink, ShouldEqual, outputNodeID("modules.db", "password"))
+	})
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// loaded hierarchy shared between http handlers
+type hierarchyServer struct {
+	mutex    sync.RWMutex
+	state    *HierarchyState
+	graph    *FlowGraph
+	loadedAt time.Time
+	load     func() (*HierarchyState, error)
+
+	// serializes full reloads with watcher updates
+	reloadMutex sync.Mutex
+	events      watchEventLog
+}
+
+func newHierarchyServer(load func() (*HierarchyState, error)) (*hierarchyServer, error) {
+	server := &hierarchyServer{load: load}
+	return server, server.reload()
+}
+
+func (s *hierarchyServer) reload() error {
+	s.reloadMutex.Lock()
+	defer s.reloadMutex.Unlock()
+
+	state, err := s.load()
+	if nil != err {
+		return err
+	}
+	s.setState(state)
+	return nil
+}
+
+func (s *hierarchyServer) setState(state *HierarchyState) {
+	graph := NewFlowGraph(state)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.state = state
+	s.graph = graph
+	s.loadedAt = time.Now()
+}
+
+// applies file changes in place, readers are blocked only while parsed files are applied
+func (s *hierarchyServer) watch(watcher *moduleWatcher, interval time.Duration) {
+	for range time.Tick(interval) {
+		s.reloadMutex.Lock()
+		changes, err := watcher.Scan()
+		if nil != err {
+			log.Error("watch: ", err)
+		} else if !changes.IsEmpty() {
+			s.mutex.Lock()
+			watcher.Apply(s.state, changes)
+			s.graph = NewFlowGraph(s.state)
+			s.loadedAt = time.Now()
+			s.mutex.Unlock()
+
+			for _, event := range s.events.Add(changes.events) {
+				log.Infof("watch: %s %s", event.Kind, event.Path)
+			}
+		}
+		s.reloadMutex.Unlock()
+	}
+}
+
+func (s *hierarchyServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/modules", s.handleModules)
+	mux.HandleFunc("/module", s.handleModule)
+	mux.HandleFunc("/trace", s.handleTrace)
+	mux.HandleFunc("/reverse-trace", s.handleReverseTrace)
+	mux.HandleFunc("/search", s.handleSearch)
+	mux.HandleFunc("/reload", s.handleReload)
+	mux.HandleFunc("/events", s.handleEvents)
+	return mux
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// handlers
+type moduleSummary struct {
+	Name      string `form:"Name" json:"Name" xml:"Name"`
+	Path      string `form:"Path" json:"Path" xml:"Path"`
+	IsLoaded  bool   `form:"IsLoaded" json:"IsLoaded" xml:"IsLoaded"`
+	Inputs    int    `form:"Inputs" json:"Inputs" xml:"Inputs"`
+	Outputs   int    `form:"Outputs" json:"Outputs" xml:"Outputs"`
+	Instances int    `form:"Instances" json:"Instances" xml:"Instances"`
+	Resources int    `form:"Resources" json:"Resources" xml:"Resources"`
+}
+
+func (s *hierarchyServer) handleModules(w http.ResponseWriter, r *http.Request) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]moduleSummary, 0, len(s.state.AllModules))
+	for _, module := range s.state.AllModules {
+		result = append(result, moduleSummary{
+			Name:      module.Name,
+			Path:      module.Path,
+			IsLoaded:  module.IsLoaded,
+			Inputs:    len(module.Inputs),
+			Outputs:   len(module.Outputs),
+			Instances: len(module.ModuleInstances),
+			Resources: len(module.Resources),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *hierarchyServer) handleModule(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	module, found := s.state.allModulesMap[name]
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Errorf("module '%s' is not found", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, module)
+}
+
+type traceResult struct {
+	Node  FlowNode   `form:"Node" json:"Node" xml:"Node"`
+	Edges []FlowEdge `form:"Edges" json:"Edges" xml:"Edges"`
+	Nodes []FlowNode `form:"Nodes" json:"Nodes" xml:"Nodes"`
+}
+
+// node is selected by module and name, kind is input by default
+func (s *hierarchyServer) traceNode(r *http.Request) (string, error) {
+	query := r.URL.Query()
+	module := query.Get("module")
+	name := query.Get("name")
+	if "" == module {
+		module = "."
+	}
+	if "" == name {
+		return "", fmt.Errorf("'name' parameter is required")
+	}
+
+	switch query.Get("kind") {
+	case "", "input":
+		return inputNodeID(module, name), nil
+	case "output":
+		return outputNodeID(module, name), nil
+	case "local":
+		return localNodeID(module, name), nil
+	case "resource":
+		parts := strings.SplitN(name, ".", 2)
+		if 2 != len(parts) {
+			return "", fmt.Errorf("resource name must be <type>.<name>")
+		}
+		return resourceNodeID(module, parts[0], parts[1]), nil
+	default:
+		return "", fmt.Errorf("unknown kind '%s', expected input, output, local or resource", query.Get("kind"))
+	}
+}
+
+func (s *hierarchyServer) handleTrace(w http.ResponseWriter, r *http.Request) {
+	s.handleWalk(w, r, func(id string) []FlowEdge { return s.graph.Downstream(id) }, func(edge FlowEdge) string { return edge.To })
+}
+
+func (s *hierarchyServer) handleReverseTrace(w http.ResponseWriter, r *http.Request) {
+	s.handleWalk(w, r, func(id string) []FlowEdge { return s.graph.Upstream(id) }, func(edge FlowEdge) string { return edge.From })
+}
+
+func (s *hierarchyServer) handleWalk(w http.ResponseWriter, r *http.Request, walk func(string) []FlowEdge, next func(FlowEdge) string) {
+	id, err := s.traceNode(r)
+	if nil != err {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	node, found := s.graph.Node(id)
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Errorf("node '%s' is not found", id))
+		return
+	}
+
+	result := traceResult{Node: node, Edges: walk(id), Nodes: []FlowNode{}}
+	for _, edge := range result.Edges {
+		if reached, found := s.graph.Node(next(edge)); found {
+			result.Nodes = append(result.Nodes, reached)
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+type resourceSearchResult struct {
+	Module   string         `form:"Module" json:"Module" xml:"Module"`
+	Resource ModuleResource `form:"Resource" json:"Resource" xml:"Resource"`
+}
+
+func (s *hierarchyServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	resourceType := r.URL.Query().Get("resource_type")
+	if "" == resourceType {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("'resource_type' parameter is required"))
+		return
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]resourceSearchResult, 0)
+	for _, module := range s.state.AllModules {
+		for _, resource := range module.Resources {
+			if resource.Type == resourceType {
+				result = append(result, resourceSearchResult{Module: module.Name, Resource: resource})
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Module != result[j].Module {
+			return result[i].Module < result[j].Module
+		}
+		return result[i].Resource.Name < result[j].Resource.Name
+	})
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *hierarchyServer) handleReload(w http.ResponseWriter, r *http.Request) {
+	if http.MethodPost != r.Method {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("reload must be requested with POST"))
+		return
+	}
+
+	err := s.reload()
+	if nil != err {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"LoadedAt": s.loadedAt, "Modules": len(s.state.AllModules)})
+}
+
+func (s *hierarchyServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	since := 0
+	if value := r.URL.Query().Get("since"); "" != value {
+		var err error
+		since, err = strconv.Atoi(value)
+		if nil != err {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("'since' must be an event sequence number: %v", err))
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, s.events.Since(since))
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(value)
+	if nil != err {
+		log.Warning("writing http response: ", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"Error": err.Error()})
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// command
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := flags.String("listen", "127.0.0.1:8080", "address to listen on")
+	watch := flags.Bool("watch", false, "watch .tf files and update the hierarchy incrementally")
+	interval := flags.Duration("interval", time.Second, "how often files are checked for changes in watch mode")
+	flags.Parse(args)
+
+	load := func() (*HierarchyState, error) {
+		state, _, err := loadState()
+		return state, err
+	}
+
+	var watcher *moduleWatcher
+	if *watch {
+		awsResources, err := loadResources(*descriptionPath)
+		if err != nil {
+			return fmt.Errorf("error loading aws resources: %v", err)
+		}
+		watcher = newModuleWatcher(*rootDir, awsResources)
+		load = watcher.Load
+	}
+
+	server, err := newHierarchyServer(load)
+	if nil != err {
+		return err
+	}
+	if nil != watcher {
+		go server.watch(watcher, *interval)
+	}
+
+	log.Infof("serving hierarchy of %s on http://%s", *rootDir, *listen)
+	return http.ListenAndServe(*listen, server.handler())
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func serveTestRequest(server *hierarchyServer, method string, url string, result interface{}) int {
+	recorder := httptest.NewRecorder()
+	server.handler().ServeHTTP(recorder, httptest.NewRequest(method, url, nil))
+	if nil != result {
+		So(json.Unmarshal(recorder.Body.Bytes(), result), ShouldBeNil)
+	}
+	return recorder.Code
+}
+
+func TestHierarchyServer(t *testing.T) {
+	loads := 0
+	server, err := newHierarchyServer(func() (*HierarchyState, error) {
+		loads++
+		return loadTestState("testdata/docs"), nil
+	})
+
+	Convey("Modules must be listed and looked up by name", t, func() {
+		So(err, ShouldBeNil)
+
+		var modules []moduleSummary
+		So(serveTestRequest(server, http.MethodGet, "/modules", &modules), ShouldEqual, http.StatusOK)
+		So(len(modules), ShouldEqual, 4)
+		So(modules[0].Name, ShouldEqual, ".")
+		So(modules[0].Inputs, ShouldEqual, 2)
+		So(modules[0].Instances, ShouldEqual, 1)
+
+		var module Module
+		So(serveTestRequest(server, http.MethodGet, "/module?name=modules.app", &module), ShouldEqual, http.StatusOK)
+		So(module.Path, ShouldEqual, "modules/app")
+
+		var failure map[string]string
+		So(serveTestRequest(server, http.MethodGet, "/module?name=modules.missing", &failure), ShouldEqual, http.StatusNotFound)
+		So(failure["Error"], ShouldContainSubstring, "modules.missing")
+	})
+
+	Convey("Traces must start at the node of the requested kind", t, func() {
+		cases := []struct {
+			url    string
+			status int
+			node   string
+		}{
+			{url: "/trace?name=ami", status: http.StatusOK, node: inputNodeID(".", "ami")},
+			{url: "/trace?module=modules.app&kind=input&name=disk", status: http.StatusOK, node: inputNodeID("modules.app", "disk")},
+			{url: "/trace?module=modules.app&kind=resource&name=aws_instance.web", status: http.StatusOK, node: resourceNodeID("modules.app", "aws_instance", "web")},
+			{url: "/reverse-trace?module=modules.app&kind=output&name=id", status: http.StatusOK, node: outputNodeID("modules.app", "id")},
+			{url: "/trace?kind=resource&name=web", status: http.StatusBadRequest},
+			{url: "/trace?kind=variable&name=ami", status: http.StatusBadRequest},
+			{url: "/trace?module=.", status: http.StatusBadRequest},
+			{url: "/trace?name=missing", status: http.StatusNotFound},
+		}
+
+		for _, c := range cases {
+			var result traceResult
+			So(serveTestRequest(server, http.MethodGet, c.url, &result), ShouldEqual, c.status)
+			if http.StatusOK == c.status {
+				So(result.Node.ID, ShouldEqual, c.node)
+			}
+		}
+
+		var result traceResult
+		serveTestRequest(server, http.MethodGet, "/trace?module=modules.app&name=image", &result)
+		So(result.Edges[0].To, ShouldEqual, resourceNodeID("modules.app", "aws_instance", "web"))
+		So(result.Nodes[0].Kind, ShouldEqual, "resource")
+	})
+
+	Convey("Resources must be searched by type", t, func() {
+		var result []resourceSearchResult
+		So(serveTestRequest(server, http.MethodGet, "/search?resource_type=aws_ebs_volume", &result), ShouldEqual, http.StatusOK)
+		So(len(result), ShouldEqual, 1)
+		So(result[0].Module, ShouldEqual, "modules.volume")
+		So(result[0].Resource.Name, ShouldEqual, "data")
+
+		So(serveTestRequest(server, http.MethodGet, "/search", nil), ShouldEqual, http.StatusBadRequest)
+	})
+
+	Convey("Reload must be requested with POST", t, func() {
+		So(serveTestRequest(server, http.MethodGet, "/reload", nil), ShouldEqual, http.StatusMethodNotAllowed)
+		So(loads, ShouldEqual, 1)
+
+		var result map[string]interface{}
+		So(serveTestRequest(server, http.MethodPost, "/reload", &result), ShouldEqual, http.StatusOK)
+		So(loads, ShouldEqual, 2)
+		So(result["Modules"], ShouldEqual, 4.0)
+	})
+
+	Convey("Events must be returned after the requested sequence number", t, func() {
+		server.events.Add([]WatchEvent{{Kind: "file-changed", Path: "main.tf"}, {Kind: "file-added", Path: "new.tf"}, {Kind: "file-removed", Path: "old.tf"}})
+
+		cases := []struct {
+			url    string
+			status int
+			events int
+		}{
+			{url: "/events", status: http.StatusOK, events: 3},
+			{url: "/events?since=0", status: http.StatusOK, events: 3},
+			{url: "/events?since=2", status: http.StatusOK, events: 1},
+			{url: "/events?since=3", status: http.StatusOK, events: 0},
+			{url: "/events?since=last", status: http.StatusBadRequest},
+		}
+
+		for _, c := range cases {
+			var events []WatchEvent
+			if http.StatusOK != c.status {
+				So(serveTestRequest(server, http.MethodGet, c.url, nil), ShouldEqual, c.status)
+				continue
+			}
+			So(serveTestRequest(server, http.MethodGet, c.url, &events), ShouldEqual, c.status)
+			So(len(events), ShouldEqual, c.events)
+		}
+	})
+
+	Convey("Traces must start at locals", t, func() {
+		server, err := newHierarchyServer(func() (*HierarchyState, error) {
+			return loadTestState("testdata/sensitive"), nil
+		})
+		So(err, ShouldBeNil)
+
+		var result traceResult
+		So(serveTestRequest(server, http.MethodGet, "/trace?kind=local&name=connection", &result), ShouldEqual, http.StatusOK)
+		So(result.Node.ID, ShouldEqual, localNodeID(".", "connection"))
+		So(result.Edges[0].To, ShouldEqual, resourceNodeID(".", "aws_instance", "app"))
+		So(result.Nodes[0].Kind, ShouldEqual, "resource")
+	})
+}
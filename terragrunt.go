@@ -0,0 +1,634 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// terragrunt units: directories with terragrunt.hcl deploying a terraform module as a root
+const terragruntFileName = "terragrunt.hcl"
+
+type TerragruntInput struct {
+	Name  string    `form:"Name" json:"Name" xml:"Name"`
+	Value string    `form:"Value" json:"Value" xml:"Value"`
+	Pos   SourcePos `form:"Pos" json:"Pos" xml:"Pos"`
+	// dependency.<name>.outputs.<output> references in the value
+	Dependencies []TerragruntOutputRef `form:"Dependencies" json:"Dependencies,omitempty" xml:"Dependencies"`
+}
+
+type TerragruntOutputRef struct {
+	Dependency string `form:"Dependency" json:"Dependency" xml:"Dependency"`
+	Output     string `form:"Output" json:"Output" xml:"Output"`
+}
+
+type TerragruntDependency struct {
+	Name       string    `form:"Name" json:"Name" xml:"Name"`
+	ConfigPath string    `form:"ConfigPath" json:"ConfigPath" xml:"ConfigPath"`
+	Unit       string    `form:"Unit" json:"Unit" xml:"Unit"`
+	Pos        SourcePos `form:"Pos" json:"Pos" xml:"Pos"`
+}
+
+type TerragruntConfig struct {
+	Pos    SourcePos `form:"Pos" json:"Pos" xml:"Pos"`
+	Source string    `form:"Source" json:"Source" xml:"Source"`
+	// module the source resolves to, called by the unit as module instance "terragrunt"
+	SourceModule string                 `form:"SourceModule" json:"SourceModule" xml:"SourceModule"`
+	Includes     []string               `form:"Includes" json:"Includes,omitempty" xml:"Includes"`
+	Inputs       []TerragruntInput      `form:"Inputs" json:"Inputs" xml:"Inputs"`
+	Dependencies []TerragruntDependency `form:"Dependencies" json:"Dependencies,omitempty" xml:"Dependencies"`
+}
+
+const terragruntInstanceName = "terragrunt"
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// loading
+func processTerragruntFile(module *Module, terraformRoot string, filePath string, state *HierarchyState) error {
+	config, err := readTerragruntConfig(terraformRoot, filepath.Dir(filePath), filePath, nil)
+	if nil != err {
+		return err
+	}
+	if "" == config.Source {
+		// configuration shared through include only
+		return nil
+	}
+
+	source := config.Source
+	if isLocalTerragruntSource(source) {
+		// module directory is given after //, the part before is copied as a whole
+		source = strings.Replace(source, "//", "/", 1)
+		name, err := terragruntModuleName(terraformRoot, filepath.Dir(filePath), source)
+		if nil != err {
+			return err
+		}
+		config.SourceModule = name
+	} else {
+		config.SourceModule = source
+	}
+
+	for i := range config.Dependencies {
+		dependency := &config.Dependencies[i]
+		name, err := terragruntModuleName(terraformRoot, filepath.Dir(filePath), dependency.ConfigPath)
+		if nil != err {
+			log.Warningf("terragrunt %s: dependency %s: %v", filePath, dependency.Name, err)
+			continue
+		}
+		dependency.Unit = name
+	}
+
+	instance := state.NewModule(config.SourceModule)
+	module.NewInstance(terragruntInstanceName, source, instance, config.Pos)
+	for _, input := range config.Inputs {
+		state.NewInput(instance, VariableID(input.Name))
+	}
+	module.Terragrunt = config
+	return nil
+}
+
+// relative paths are resolved against the unit directory, get_terragrunt_dir() makes them absolute
+func terragruntModuleName(terraformRoot string, unitDir string, path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(unitDir, path)
+	}
+	absPath, err := filepath.Abs(path)
+	if nil != err {
+		return "", err
+	}
+	absRoot, err := filepath.Abs(terraformRoot)
+	if nil != err {
+		return "", err
+	}
+	dir, err := filepath.Rel(absRoot, absPath)
+	if nil != err {
+		return "", err
+	}
+	if strings.HasPrefix(dir, "..") {
+		return "", fmt.Errorf("%s is outside of %s", path, terraformRoot)
+	}
+	return getModuleName(terraformRoot, dir), nil
+}
+
+func isLocalTerragruntSource(source string) bool {
+	return strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") || filepath.IsAbs(source)
+}
+
+// included configurations are merged in first, the including file wins,
+// expressions of included files are evaluated in the unit directory as terragrunt does
+func readTerragruntConfig(terraformRoot string, unitDir string, filePath string, visited []string) (*TerragruntConfig, error) {
+	if Include(visited, filePath) {
+		return nil, fmt.Errorf("terragrunt %s: include cycle", filePath)
+	}
+	visited = append(visited, filePath)
+
+	content, err := ioutil.ReadFile(filePath)
+	if nil != err {
+		return nil, fmt.Errorf("terragrunt %s: %v", filePath, err)
+	}
+	body, err := parseTerragruntBody(string(content))
+	if nil != err {
+		return nil, fmt.Errorf("terragrunt %s: %v", filePath, err)
+	}
+
+	config := &TerragruntConfig{Pos: SourcePos{Filename: filePath, Line: 1, EndLine: body.EndLine}}
+	dir := unitDir
+
+	for _, block := range body.Blocks {
+		if "include" != block.Type {
+			continue
+		}
+		pathAttr, found := block.Body.Attribute("path")
+		if !found {
+			continue
+		}
+		includePath := resolveTerragruntPath(terraformRoot, dir, pathAttr.Expr)
+		if "" == includePath {
+			log.Warningf("terragrunt %s:%d: include path '%s' is not supported (SKIPPED)", filePath, pathAttr.Line, pathAttr.Expr)
+			continue
+		}
+		included, err := readTerragruntConfig(terraformRoot, unitDir, includePath, visited)
+		if nil != err {
+			log.Warningf("%v (SKIPPED)", err)
+			continue
+		}
+		config.Includes = append(config.Includes, includePath)
+		config.Includes = append(config.Includes, included.Includes...)
+		config.Source = included.Source
+		config.Inputs = included.Inputs
+		config.Dependencies = included.Dependencies
+	}
+
+	for _, block := range body.Blocks {
+		pos := SourcePos{Filename: filePath, Line: block.Line, EndLine: block.EndLine}
+		switch block.Type {
+		case "terraform":
+			if source, found := block.Body.Attribute("source"); found {
+				config.Source = evalTerragruntString(source.Expr, dir, config.Includes)
+			}
+		case "dependency":
+			if 1 != len(block.Labels) {
+				continue
+			}
+			configPath, _ := block.Body.Attribute("config_path")
+			dependency := TerragruntDependency{Name: block.Labels[0], ConfigPath: evalTerragruntString(configPath.Expr, dir, config.Includes), Pos: pos}
+			config.Dependencies = replaceTerragruntDependency(config.Dependencies, dependency)
+		}
+	}
+
+	if inputs, found := body.Attribute("inputs"); found && nil != inputs.Object {
+		for _, attr := range inputs.Object.Attributes {
+			input := TerragruntInput{Name: attr.Name, Value: attr.Expr, Pos: SourcePos{Filename: filePath, Line: attr.Line, EndLine: attr.EndLine}}
+			for _, match := range terragruntDependencyRegexp.FindAllStringSubmatch(attr.Expr, -1) {
+				input.Dependencies = append(input.Dependencies, TerragruntOutputRef{Dependency: match[1], Output: match[2]})
+			}
+			config.Inputs = replaceTerragruntInput(config.Inputs, input)
+		}
+	}
+	return config, nil
+}
+
+var terragruntDependencyRegexp = regexp.MustCompile("dependency\\.([-a-zA-Z_0-9]*)\\.outputs\\.([-a-zA-Z_0-9]*)")
+
+func replaceTerragruntInput(inputs []TerragruntInput, input TerragruntInput) []TerragruntInput {
+	result := make([]TerragruntInput, 0, len(inputs)+1)
+	for _, existing := range inputs {
+		if existing.Name != input.Name {
+			result = append(result, existing)
+		}
+	}
+	return append(result, input)
+}
+
+func replaceTerragruntDependency(dependencies []TerragruntDependency, dependency TerragruntDependency) []TerragruntDependency {
+	result := make([]TerragruntDependency, 0, len(dependencies)+1)
+	for _, existing := range dependencies {
+		if existing.Name != dependency.Name {
+			result = append(result, existing)
+		}
+	}
+	return append(result, dependency)
+}
+
+var findInParentFoldersRegexp = regexp.MustCompile("^find_in_parent_folders\\(\\s*(\"[^\"]*\")?\\s*\\)$")
+
+// path of an include, empty when the expression can not be evaluated
+func resolveTerragruntPath(terraformRoot string, dir string, expr string) string {
+	expr = strings.TrimSpace(expr)
+	if matches := findInParentFoldersRegexp.FindStringSubmatch(expr); nil != matches {
+		name := terragruntFileName
+		if "" != matches[1] {
+			name = unquote(matches[1])
+		}
+		return findInParentFolders(terraformRoot, dir, name)
+	}
+
+	path := evalTerragruntString(expr, dir, nil)
+	if "" == path || strings.Contains(path, "${") {
+		return ""
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return path
+}
+
+// searched up to the terraform root, files outside of it are not analyzed
+func findInParentFolders(terraformRoot string, dir string, name string) string {
+	root, err := filepath.Abs(terraformRoot)
+	if nil != err {
+		return ""
+	}
+	for current := filepath.Dir(dir); ; current = filepath.Dir(current) {
+		path := filepath.Join(current, name)
+		if _, err := os.Stat(path); nil == err {
+			return path
+		}
+		abs, err := filepath.Abs(current)
+		if nil != err || abs == root || filepath.Dir(current) == current {
+			return ""
+		}
+	}
+}
+
+// string literals, get_terragrunt_dir() and get_parent_terragrunt_dir() interpolations are evaluated, the rest is kept as is
+func evalTerragruntString(expr string, dir string, includes []string) string {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "\"") {
+		return expr
+	}
+	value := unquote(expr)
+	if absDir, err := filepath.Abs(dir); nil == err {
+		value = strings.Replace(value, "${get_terragrunt_dir()}", absDir, -1)
+	}
+	if 0 != len(includes) {
+		if absDir, err := filepath.Abs(filepath.Dir(includes[0])); nil == err {
+			value = strings.Replace(value, "${get_parent_terragrunt_dir()}", absDir, -1)
+		}
+	}
+	return value
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// dependency outputs wired to inputs of the unit module
+type TerragruntLink struct {
+	Unit       string `form:"Unit" json:"Unit" xml:"Unit"`
+	Input      string `form:"Input" json:"Input" xml:"Input"`
+	Dependency string `form:"Dependency" json:"Dependency" xml:"Dependency"`
+	// unit producing the output and the module it deploys
+	SourceUnit   string `form:"SourceUnit" json:"SourceUnit" xml:"SourceUnit"`
+	SourceModule string `form:"SourceModule" json:"SourceModule" xml:"SourceModule"`
+	Output       string `form:"Output" json:"Output" xml:"Output"`
+	Error        string `form:"Error" json:"Error,omitempty" xml:"Error"`
+}
+
+func LinkTerragruntDependencies(state *HierarchyState) []TerragruntLink {
+	var links []TerragruntLink
+	for _, module := range state.AllModules {
+		if nil == module.Terragrunt {
+			continue
+		}
+		for _, input := range module.Terragrunt.Inputs {
+			for _, ref := range input.Dependencies {
+				link := TerragruntLink{Unit: module.Name, Input: input.Name, Dependency: ref.Dependency, Output: ref.Output}
+				dependency, found := findTerragruntDependency(module.Terragrunt, ref.Dependency)
+				source, sourceFound := state.allModulesMap[dependency.Unit]
+				switch {
+				case !found:
+					link.Error = fmt.Sprintf("dependency %s is not declared", ref.Dependency)
+				case !sourceFound || nil == source.Terragrunt:
+					link.SourceUnit = dependency.Unit
+					link.Error = fmt.Sprintf("dependency %s: %s is not a terragrunt unit", ref.Dependency, dependency.ConfigPath)
+				default:
+					link.SourceUnit = source.Name
+					link.SourceModule = source.Terragrunt.SourceModule
+				}
+				links = append(links, link)
+			}
+		}
+	}
+	sort.SliceStable(links, func(i, j int) bool { return links[i].Unit < links[j].Unit })
+	return links
+}
+
+func findTerragruntDependency(config *TerragruntConfig, name string) (TerragruntDependency, bool) {
+	for _, dependency := range config.Dependencies {
+		if dependency.Name == name {
+			return dependency, true
+		}
+	}
+	return TerragruntDependency{}, false
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// minimal reader of hcl2 syntax used in terragrunt.hcl: blocks, attributes and object literals,
+// expressions are kept as source text
+type tgAttribute struct {
+	Name    string
+	Expr    string
+	Line    int
+	EndLine int
+	// set when the expression is an object literal
+	Object *tgBody
+}
+
+type tgBlock struct {
+	Type    string
+	Labels  []string
+	Body    *tgBody
+	Line    int
+	EndLine int
+}
+
+type tgBody struct {
+	Attributes []tgAttribute
+	Blocks     []tgBlock
+	EndLine    int
+}
+
+func (b *tgBody) Attribute(name string) (tgAttribute, bool) {
+	for _, attr := range b.Attributes {
+		if attr.Name == name {
+			return attr, true
+		}
+	}
+	return tgAttribute{}, false
+}
+
+type tgScanner struct {
+	src  string
+	pos  int
+	line int
+}
+
+func parseTerragruntBody(src string) (*tgBody, error) {
+	scanner := &tgScanner{src: src, line: 1}
+	body, err := scanner.body(false)
+	if nil != err {
+		return nil, fmt.Errorf("line %d: %v", scanner.line, err)
+	}
+	return body, nil
+}
+
+func (s *tgScanner) peek() byte {
+	if s.pos < len(s.src) {
+		return s.src[s.pos]
+	}
+	return 0
+}
+
+func (s *tgScanner) next() byte {
+	c := s.src[s.pos]
+	s.pos++
+	if '\n' == c {
+		s.line++
+	}
+	return c
+}
+
+// skips spaces and comments, new lines too when newlines is set
+func (s *tgScanner) skip(newlines bool) {
+	for s.pos < len(s.src) {
+		c := s.peek()
+		switch {
+		case ' ' == c || '\t' == c || '\r' == c || ('\n' == c && newlines):
+			s.next()
+		case '#' == c || strings.HasPrefix(s.src[s.pos:], "//"):
+			for s.pos < len(s.src) && '\n' != s.peek() {
+				s.next()
+			}
+		case strings.HasPrefix(s.src[s.pos:], "/*"):
+			for s.pos < len(s.src) && !strings.HasPrefix(s.src[s.pos:], "*/") {
+				s.next()
+			}
+			if s.pos < len(s.src) {
+				s.pos += 2
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (s *tgScanner) identifier() string {
+	start := s.pos
+	for s.pos < len(s.src) {
+		c := s.peek()
+		if !('_' == c || '-' == c || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')) {
+			break
+		}
+		s.pos++
+	}
+	return s.src[start:s.pos]
+}
+
+func (s *tgScanner) quoted() (string, error) {
+	start := s.pos
+	s.next()
+	for s.pos < len(s.src) {
+		c := s.next()
+		if '\\' == c && s.pos < len(s.src) {
+			s.next()
+		} else if '"' == c {
+			return s.src[start:s.pos], nil
+		} else if '\n' == c {
+			break
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+// body of the file or of a block, attributes are separated by new lines
+func (s *tgScanner) body(inBlock bool) (*tgBody, error) {
+	body := &tgBody{}
+	for {
+		s.skip(true)
+		if s.pos >= len(s.src) {
+			if inBlock {
+				return nil, fmt.Errorf("unexpected end of file, '}' expected")
+			}
+			body.EndLine = s.line
+			return body, nil
+		}
+		if '}' == s.peek() {
+			if !inBlock {
+				return nil, fmt.Errorf("unexpected '}'")
+			}
+			body.EndLine = s.line
+			s.next()
+			return body, nil
+		}
+
+		line := s.line
+		name := s.identifier()
+		if "" == name {
+			return nil, fmt.Errorf("unexpected '%c'", s.peek())
+		}
+		s.skip(false)
+
+		if '=' == s.peek() {
+			s.next()
+			attr, err := s.attribute(name, line, "\n")
+			if nil != err {
+				return nil, err
+			}
+			body.Attributes = append(body.Attributes, attr)
+			continue
+		}
+
+		block := tgBlock{Type: name, Line: line}
+		for '{' != s.peek() {
+			switch {
+			case '"' == s.peek():
+				label, err := s.quoted()
+				if nil != err {
+					return nil, err
+				}
+				block.Labels = append(block.Labels, unquote(label))
+			default:
+				label := s.identifier()
+				if "" == label {
+					return nil, fmt.Errorf("'=' or '{' expected after '%s'", name)
+				}
+				block.Labels = append(block.Labels, label)
+			}
+			s.skip(false)
+		}
+		s.next()
+		blockBody, err := s.body(true)
+		if nil != err {
+			return nil, err
+		}
+		block.Body = blockBody
+		block.EndLine = blockBody.EndLine
+		body.Blocks = append(body.Blocks, block)
+	}
+}
+
+// expression up to one of the terminators outside of brackets and strings, object literals are parsed too
+func (s *tgScanner) attribute(name string, line int, terminators string) (tgAttribute, error) {
+	s.skip(false)
+	attr := tgAttribute{Name: name, Line: line}
+	start := s.pos
+
+	if '{' == s.peek() {
+		s.next()
+		object, err := s.object()
+		if nil != err {
+			return attr, err
+		}
+		attr.Object = object
+		attr.Expr = strings.TrimSpace(s.src[start:s.pos])
+		attr.EndLine = s.line
+		return attr, nil
+	}
+
+	// comments are dropped from the expression text
+	var expr strings.Builder
+	depth := 0
+	for s.pos < len(s.src) {
+		c := s.peek()
+		if 0 == depth && strings.IndexByte(terminators, c) >= 0 {
+			break
+		}
+		switch {
+		case '"' == c:
+			if _, err := s.quoted(); nil != err {
+				return attr, err
+			}
+			continue
+		case strings.HasPrefix(s.src[s.pos:], "<<"):
+			if err := s.heredoc(); nil != err {
+				return attr, err
+			}
+			continue
+		case '(' == c || '[' == c || '{' == c:
+			depth++
+		case ')' == c || ']' == c || '}' == c:
+			depth--
+		case '#' == c || strings.HasPrefix(s.src[s.pos:], "//") || strings.HasPrefix(s.src[s.pos:], "/*"):
+			expr.WriteString(s.src[start:s.pos])
+			s.skip(false)
+			start = s.pos
+			continue
+		}
+		s.next()
+	}
+	expr.WriteString(s.src[start:s.pos])
+	attr.Expr = strings.TrimSpace(expr.String())
+	attr.EndLine = s.line
+	return attr, nil
+}
+
+func (s *tgScanner) heredoc() error {
+	s.pos += 2
+	if '-' == s.peek() || '~' == s.peek() {
+		s.next()
+	}
+	marker := s.identifier()
+	for s.pos < len(s.src) {
+		s.next()
+		if '\n' == s.src[s.pos-1] {
+			end := strings.IndexByte(s.src[s.pos:], '\n')
+			if end < 0 {
+				end = len(s.src) - s.pos
+			}
+			if strings.TrimSpace(s.src[s.pos:s.pos+end]) == marker {
+				s.pos += end
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("unterminated heredoc %s", marker)
+}
+
+// object literal after '{', items are separated by new lines or commas, keys may be quoted
+func (s *tgScanner) object() (*tgBody, error) {
+	object := &tgBody{}
+	for {
+		s.skip(true)
+		for ',' == s.peek() {
+			s.next()
+			s.skip(true)
+		}
+		if s.pos >= len(s.src) {
+			return nil, fmt.Errorf("unexpected end of file, '}' expected")
+		}
+		if '}' == s.peek() {
+			object.EndLine = s.line
+			s.next()
+			return object, nil
+		}
+
+		line := s.line
+		var key string
+		if '"' == s.peek() {
+			quoted, err := s.quoted()
+			if nil != err {
+				return nil, err
+			}
+			key = unquote(quoted)
+		} else {
+			key = s.identifier()
+		}
+		if "" == key {
+			return nil, fmt.Errorf("object key expected, got '%c'", s.peek())
+		}
+		s.skip(false)
+		if '=' != s.peek() && ':' != s.peek() {
+			return nil, fmt.Errorf("'=' expected after object key '%s'", key)
+		}
+		s.next()
+
+		attr, err := s.attribute(key, line, "\n,}")
+		if nil != err {
+			return nil, err
+		}
+		object.Attributes = append(object.Attributes, attr)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseTerragruntBody(t *testing.T) {
+	Convey("Terragrunt files must be read without evaluating expressions", t, func() {
+		body, err := parseTerragruntBody(`
+# comment
+include {
+  path = find_in_parent_folders()
+}
+dependency "vpc" {
+  config_path = "../vpc" // trailing comment
+}
+inputs = {
+  "quoted" : 1, list = [1, 2,
+    3]
+  text = <<EOT
+}
+EOT
+  id = dependency.vpc.outputs.id
+}
+`)
+		So(err, ShouldBeNil)
+		So(len(body.Blocks), ShouldEqual, 2)
+		So(body.Blocks[1].Labels, ShouldResemble, []string{"vpc"})
+		path, found := body.Blocks[0].Body.Attribute("path")
+		So(found, ShouldBeTrue)
+		So(path.Expr, ShouldEqual, "find_in_parent_folders()")
+		configPath, found := body.Blocks[1].Body.Attribute("config_path")
+		So(found, ShouldBeTrue)
+		So(configPath.Expr, ShouldEqual, `"../vpc"`)
+
+		inputs, found := body.Attribute("inputs")
+		So(found, ShouldBeTrue)
+		So(len(inputs.Object.Attributes), ShouldEqual, 4)
+		So(inputs.Object.Attributes[1].Expr, ShouldEqual, "[1, 2,\n    3]")
+		So(inputs.Object.Attributes[3].Line, ShouldEqual, 15)
+
+		_, err = parseTerragruntBody("inputs = {\n  a = 1\n")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestTerragruntUnits(t *testing.T) {
+	Convey("Terragrunt units must call their source modules and link dependency outputs", t, func() {
+		state := loadTestState("testdata/terragrunt")
+
+		app := state.allModulesMap["live.app"]
+		So(app.Terragrunt, ShouldNotBeNil)
+		So(app.Terragrunt.SourceModule, ShouldEqual, "modules.app")
+		So(app.ModuleInstances[0].ModulePath, ShouldEqual, "modules.app")
+		So(app.Terragrunt.Includes, ShouldResemble, []string{"testdata/terragrunt/terragrunt.hcl"})
+
+		inputs := make(map[string]string)
+		for _, input := range app.Terragrunt.Inputs {
+			inputs[input.Name] = input.Value
+		}
+		So(inputs["name"], ShouldEqual, `"app"`)
+		So(inputs["region"], ShouldEqual, `"us-east-1"`)
+		So(len(inputs), ShouldEqual, 4)
+		So(app.Terragrunt.Dependencies[0].ConfigPath, ShouldEqual, "../vpc")
+		So(app.Terragrunt.Dependencies[0].Unit, ShouldEqual, "live.vpc")
+		So(state.allModulesMap["live.vpc"].Terragrunt.Source, ShouldEqual, "../../modules//vpc")
+		So(state.allModulesMap["live.vpc"].Terragrunt.SourceModule, ShouldEqual, "modules.vpc")
+		So(state.allModulesMap["."].Terragrunt, ShouldBeNil)
+
+		links := LinkTerragruntDependencies(state)
+		So(len(links), ShouldEqual, 1)
+		So(links[0].SourceUnit, ShouldEqual, "live.vpc")
+		So(links[0].SourceModule, ShouldEqual, "modules.vpc")
+		So(links[0].Error, ShouldEqual, "")
+
+		graph := NewFlowGraph(state)
+		edges := graph.Downstream(outputNodeID("modules.vpc", "vpc_id"))
+		So(len(edges), ShouldEqual, 2)
+		So(edges[0].To, ShouldEqual, inputNodeID("modules.app", "vpc_id"))
+		So(edges[0].Kind, ShouldEqual, "FromDependency")
+		So(edges[1].To, ShouldEqual, resourceNodeID("modules.app", "aws_security_group", "app"))
+
+		var roots []string
+		for _, root := range workspaceRoots(state) {
+			roots = append(roots, root.Path)
+		}
+		So(roots, ShouldResemble, []string{"live/app", "live/vpc"})
+	})
+}
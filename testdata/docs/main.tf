@@ -0,0 +1,13 @@
+variable "ami" {
+  description = "image of the web servers | latest by default"
+}
+
+variable "disk_size" {
+  default = 10
+}
+
+module "app" {
+  source = "./modules/app"
+  image  = "${var.ami}"
+  disk   = "${var.disk_size}"
+}
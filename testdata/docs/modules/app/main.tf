@@ -0,0 +1,23 @@
+variable "image" {
+  type = "string"
+}
+
+variable "disk" {}
+
+resource "aws_instance" "web" {
+  ami = "${var.image}"
+}
+
+module "volume" {
+  source = "../volume"
+  size   = "${var.disk}"
+}
+
+output "id" {
+  description = "instance id"
+  value       = "${aws_instance.web.id}"
+}
+
+output "volume_id" {
+  value = "${module.volume.id}"
+}
@@ -0,0 +1,9 @@
+variable "size" {}
+
+resource "aws_ebs_volume" "data" {
+  size = "${var.size}"
+}
+
+output "id" {
+  value = "${aws_ebs_volume.data.id}"
+}
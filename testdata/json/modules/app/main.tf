@@ -0,0 +1,12 @@
+variable "image" {}
+
+variable "size" {}
+
+resource "aws_instance" "web" {
+  ami   = "${var.image}"
+  count = "${var.size}"
+}
+
+output "ip" {
+  value = "${aws_instance.web.public_ip}"
+}
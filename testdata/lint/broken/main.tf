@@ -0,0 +1,5 @@
+variable "a" {
+  default = 
+}
+
+resource "aws_instance" {{
@@ -0,0 +1,26 @@
+variable "region" {
+  description = "aws region"
+}
+
+# hierarchy:ignore unused-variable
+variable "legacy" {
+  description = "kept for old pipelines"
+}
+
+variable "unused" {
+  description = "nobody reads it"
+}
+
+module "app" {
+  source = "./modules/app"
+  region = "${var.region}"
+  zone   = "${var.region}a"
+}
+
+module "missing" {
+  source = "./modules/missing"
+}
+
+output "app_id" {
+  value = "${module.app.id}"
+}
@@ -0,0 +1,13 @@
+variable "region" {
+  description = "aws region"
+}
+
+resource "aws_instance" "app" {
+  availability_zone = "${var.region}"
+  key_name          = "${var.key_name}"
+}
+
+output "id" {
+  description = "instance id"
+  value       = "${aws_instance.app.id}"
+}
@@ -0,0 +1,27 @@
+# var.commented is only mentioned here
+variable "commented" {
+  description = "read by nothing"
+}
+
+variable "documented" {
+  description = <<EOT
+mentions var.documented in its own docs
+EOT
+}
+
+variable "exported" {
+  description = "only passed through an output"
+}
+
+variable "looked_up" {
+  description = "only used by a data source"
+}
+
+data "aws_ami" "base" {
+  name_regex = "${var.looked_up}"
+}
+
+output "exported" {
+  description = "the exported value"
+  value       = "${var.exported}"
+}
@@ -0,0 +1,12 @@
+variable "ami" {
+  description = "image of the web servers"
+}
+
+module "app" {
+  source = "./modules/app"
+  image  = "${var.ami}"
+}
+
+output "app_id" {
+  value = "${module.app.id}"
+}
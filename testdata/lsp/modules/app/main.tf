@@ -0,0 +1,12 @@
+variable "image" {
+  type = "string"
+}
+
+resource "aws_instance" "web" {
+  ami = "${var.image}"
+}
+
+output "id" {
+  description = "instance id"
+  value       = "${aws_instance.web.id}"
+}
@@ -0,0 +1,18 @@
+variable "aws_region" {}
+
+variable "InstanceType" {}
+
+module "Web" {
+  source        = "./modules/web"
+  region        = "${var.aws_region}"
+  instance_type = "${var.InstanceType}"
+  name          = "${var.aws_region}-web"
+}
+
+resource "aws_eip" "webIP" {
+  instance = "${module.Web.id}"
+}
+
+output "web-ip" {
+  value = "${aws_eip.webIP.public_ip}"
+}
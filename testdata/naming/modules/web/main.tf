@@ -0,0 +1,15 @@
+variable "region" {}
+
+variable "instance_type" {}
+
+variable "name" {}
+
+resource "aws_instance" "web" {
+  availability_zone = "${var.region}"
+  instance_type     = "${var.instance_type}"
+  tags              = "${var.name}"
+}
+
+output "id" {
+  value = "${aws_instance.web.id}"
+}
@@ -0,0 +1,43 @@
+variable "ami" {}
+
+variable "patched_ami" {}
+
+variable "size" {
+  default = 1
+}
+
+resource "aws_instance" "web" {
+  ami   = "${var.ami}"
+  count = "${var.size}"
+
+  lifecycle {
+    create_before_destroy = true
+  }
+}
+
+variable "x" {}
+
+variable "y" {}
+
+locals {
+  a = "1"
+}
+
+locals {
+  b = "${var.x}"
+}
+
+terraform {
+  required_version = ">= 0.12"
+}
+
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 3.0"
+    }
+
+    random = "~> 2.0"
+  }
+}
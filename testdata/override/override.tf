@@ -0,0 +1,12 @@
+locals {
+  b = "${var.y}"
+}
+
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 4.0"
+    }
+  }
+}
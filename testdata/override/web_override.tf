@@ -0,0 +1,15 @@
+variable "size" {
+  default = 2
+}
+
+resource "aws_instance" "web" {
+  ami = "${var.patched_ami}"
+
+  lifecycle {
+    prevent_destroy = true
+  }
+}
+
+resource "aws_instance" "missing" {
+  ami = "${var.ami}"
+}
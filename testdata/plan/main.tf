@@ -0,0 +1,11 @@
+variable "ami" {}
+
+variable "instance_count" {
+  default = 1
+}
+
+module "app" {
+  source = "./modules/app"
+  image  = "${var.ami}"
+  size   = "${var.instance_count}"
+}
@@ -0,0 +1,12 @@
+variable "image" {}
+
+variable "size" {}
+
+resource "aws_instance" "web" {
+  ami   = "${var.image}"
+  count = "${var.size}"
+}
+
+resource "aws_eip" "web" {
+  instance = "${aws_instance.web.id}"
+}
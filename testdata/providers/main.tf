@@ -0,0 +1,20 @@
+provider "aws" {
+  region = "eu-west-1"
+}
+
+provider "aws" {
+  alias  = "us"
+  region = "us-east-1"
+}
+
+module "app" {
+  source = "./modules/app"
+}
+
+module "app_us" {
+  source = "./modules/app"
+
+  providers = {
+    "aws" = "aws.us"
+  }
+}
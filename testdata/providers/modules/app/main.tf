@@ -0,0 +1,7 @@
+resource "aws_instance" "web" {
+  ami = "ami-1"
+}
+
+module "db" {
+  source = "../db"
+}
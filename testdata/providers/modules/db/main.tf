@@ -0,0 +1,13 @@
+provider "aws" {
+  alias  = "replica"
+  region = "ap-south-1"
+}
+
+resource "aws_db_instance" "main" {
+  engine = "postgres"
+}
+
+resource "aws_db_instance" "replica" {
+  provider = "aws.replica"
+  engine   = "postgres"
+}
@@ -0,0 +1,43 @@
+data "terraform_remote_state" "network" {
+  backend = "s3"
+
+  config {
+    bucket = "infra-state"
+    key    = "network/terraform.tfstate"
+  }
+}
+
+data "terraform_remote_state" "db" {
+  backend = "local"
+
+  config {
+    path = "../db/terraform.tfstate"
+  }
+}
+
+resource "aws_security_group" "app" {
+  vpc_id = "${data.terraform_remote_state.network.vpc_id}"
+}
+
+output "database" {
+  value = "${data.terraform_remote_state.db.outputs.endpoint}"
+}
+
+output "subnet" {
+  value = "${data.terraform_remote_state.network.subnet_id}"
+}
+
+variable "environment" {}
+
+data "terraform_remote_state" "shared" {
+  backend = "s3"
+
+  config {
+    bucket = "infra-state"
+    key    = "${var.environment}/terraform.tfstate"
+  }
+}
+
+resource "aws_route53_record" "app" {
+  zone_id = "${data.terraform_remote_state.shared.zone_id}"
+}
@@ -0,0 +1,7 @@
+resource "aws_db_instance" "main" {
+  engine = "postgres"
+}
+
+output "endpoint" {
+  value = "${aws_db_instance.main.endpoint}"
+}
@@ -0,0 +1,15 @@
+terraform {
+  backend "s3" {
+    bucket = "infra-state"
+    key    = "dns/terraform.tfstate"
+    region = "eu-west-1"
+  }
+}
+
+resource "aws_route53_zone" "main" {
+  name = "example.com"
+}
+
+output "zone_id" {
+  value = "${aws_route53_zone.main.zone_id}"
+}
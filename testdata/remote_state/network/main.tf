@@ -0,0 +1,15 @@
+terraform {
+  backend "s3" {
+    bucket = "infra-state"
+    key    = "network/terraform.tfstate"
+    region = "eu-west-1"
+  }
+}
+
+resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+
+output "vpc_id" {
+  value = "${aws_vpc.main.id}"
+}
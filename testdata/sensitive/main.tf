@@ -0,0 +1,45 @@
+variable "db_password" {
+  sensitive = true
+}
+
+variable "environment" {}
+
+locals {
+  connection = "postgres://admin:${var.db_password}@db"
+  labels     = "${var.environment}"
+}
+
+module "db" {
+  source   = "./modules/db"
+  password = "${var.db_password}"
+}
+
+resource "aws_instance" "app" {
+  user_data     = "${local.connection}"
+  instance_type = "t2.micro"
+  tags          = "${local.labels}"
+}
+
+resource "aws_s3_bucket" "backup" {
+  bucket = "backup"
+
+  tags {
+    Owner  = "${var.environment}"
+    Secret = "${var.db_password}"
+  }
+}
+
+resource "aws_sqs_queue" "jobs" {
+  tags = {
+    Name = "jobs-${var.db_password}"
+  }
+}
+
+output "db_password" {
+  value     = "${module.db.password}"
+  sensitive = true
+}
+
+output "db_endpoint" {
+  value = "${module.db.endpoint}"
+}
@@ -0,0 +1,14 @@
+variable "password" {}
+
+resource "aws_db_instance" "main" {
+  password    = "${var.password}"
+  description = "managed by terraform"
+}
+
+output "password" {
+  value = "${aws_db_instance.main.password}"
+}
+
+output "endpoint" {
+  value = "${aws_db_instance.main.endpoint}"
+}
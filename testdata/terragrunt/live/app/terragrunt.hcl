@@ -0,0 +1,27 @@
+include {
+  path = find_in_parent_folders()
+}
+
+terraform {
+  source = "${get_terragrunt_dir()}/../../modules//app"
+}
+
+dependency "vpc" {
+  config_path = "../vpc" // sibling unit
+
+  mock_outputs = {
+    vpc_id = "vpc-mock"
+  }
+}
+
+inputs = {
+  // overrides the shared name
+  name   = "app"
+  vpc_id = dependency.vpc.outputs.vpc_id
+  tags = {
+    "Team" = "platform",
+    Notes  = <<EOT
+managed by terragrunt }
+EOT
+  }
+}
@@ -0,0 +1,11 @@
+include {
+  path = find_in_parent_folders()
+}
+
+terraform {
+  source = "../../modules//vpc" # pinned
+}
+
+inputs = {
+  cidr = "10.0.0.0/16"
+}
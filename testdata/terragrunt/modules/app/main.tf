@@ -0,0 +1,10 @@
+variable "vpc_id" {}
+
+variable "name" {}
+
+variable "region" {}
+
+resource "aws_security_group" "app" {
+  name   = "${var.name}"
+  vpc_id = "${var.vpc_id}"
+}
@@ -0,0 +1,11 @@
+variable "cidr" {}
+
+variable "region" {}
+
+resource "aws_vpc" "main" {
+  cidr_block = "${var.cidr}"
+}
+
+output "vpc_id" {
+  value = "${aws_vpc.main.id}"
+}
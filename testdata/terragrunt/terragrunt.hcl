@@ -0,0 +1,13 @@
+# shared by every unit
+remote_state {
+  backend = "s3"
+  config = {
+    bucket = "infra-state"
+    key    = "${path_relative_to_include()}/terraform.tfstate"
+  }
+}
+
+inputs = {
+  region = "us-east-1"
+  name   = "default"
+}
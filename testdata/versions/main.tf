@@ -0,0 +1,21 @@
+terraform {
+  required_version = ">= 0.11.0"
+
+  backend "s3" {
+    bucket = "state"
+    key    = "live/terraform.tfstate"
+  }
+}
+
+provider "aws" {
+  version = ">= 2.7"
+  region  = "eu-west-1"
+}
+
+module "network" {
+  source = "./modules/network"
+}
+
+module "legacy" {
+  source = "./modules/legacy"
+}
@@ -0,0 +1,13 @@
+terraform {
+  required_providers {
+    aws = "< 2.5"
+  }
+}
+
+resource "aws_instance" "web" {
+  ami = "ami-1"
+}
+
+resource "random_id" "suffix" {
+  byte_length = 4
+}
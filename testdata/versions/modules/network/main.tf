@@ -0,0 +1,11 @@
+terraform {
+  required_version = "~> 0.11.14"
+
+  required_providers {
+    aws = "~> 2.0"
+  }
+}
+
+resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
@@ -0,0 +1,14 @@
+terraform {
+  backend "s3" {
+    bucket = "infra-state"
+    key    = "prod/terraform.tfstate"
+  }
+}
+
+provider "aws" {
+  region = "eu-west-1"
+}
+
+module "app" {
+  source = "../../modules/app"
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/hcl/ast"
+	jsonParser "github.com/hashicorp/hcl/json/parser"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// terraform json configuration syntax (*.tf.json)
+func isJSONModuleFileName(filePath string) bool {
+	return strings.HasSuffix(filePath, ".tf.json")
+}
+
+// blocks are flattened by the hcl json parser into the same shape as native syntax,
+// positions are lost there, so they are restored from the source
+func parseJSONModuleFile(content []byte) (*ast.File, error) {
+	file, err := jsonParser.Parse(content)
+	if nil != err {
+		return nil, err
+	}
+
+	positions, err := jsonBlockPositions(content)
+	if nil != err {
+		return nil, err
+	}
+
+	objects, ok := file.Node.(*ast.ObjectList)
+	if !ok {
+		return file, nil
+	}
+	for _, item := range objects.Items {
+		var keys []string
+		for _, key := range item.Keys {
+			keys = append(keys, objectKeyText(key))
+		}
+		pos, found := positions[strings.Join(keys, ".")]
+		if !found {
+			continue
+		}
+		for _, key := range item.Keys {
+			key.Token.Pos.Line = pos.Line
+		}
+		item.Assign.Line = pos.Line
+		if value, ok := item.Val.(*ast.ObjectType); ok {
+			value.Lbrace.Line = pos.Line
+			value.Rbrace.Line = pos.EndLine
+		}
+	}
+	return file, nil
+}
+
+// json keys keep their quotes in the ast
+func objectKeyText(key *ast.ObjectKey) string {
+	if key.Token.JSON {
+		return unquote(key.Token.Text)
+	}
+	return key.Token.Text
+}
+
+// joined key path of objects up to block depth (resource.type.name) -> lines from the block name to its closing brace
+func jsonBlockPositions(content []byte) (map[string]SourcePos, error) {
+	var lineStarts []int
+	lineStarts = append(lineStarts, 0)
+	for i, c := range content {
+		if '\n' == c {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	lineAt := func(offset int64) int {
+		return sort.Search(len(lineStarts), func(i int) bool { return int64(lineStarts[i]) > offset })
+	}
+
+	positions := make(map[string]SourcePos)
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var walk func(path []string) (bool, error)
+	walk = func(path []string) (bool, error) {
+		token, err := decoder.Token()
+		if nil != err {
+			return false, err
+		}
+
+		switch token {
+		case json.Delim('{'):
+			for decoder.More() {
+				keyStart := skipJSONSpace(content, decoder.InputOffset())
+				key, err := decoder.Token()
+				if nil != err {
+					return false, err
+				}
+				keyPath := append(append([]string{}, path...), fmt.Sprint(key))
+				isObject, err := walk(keyPath)
+				if nil != err {
+					return false, err
+				}
+				if isObject && len(keyPath) <= 3 {
+					positions[strings.Join(keyPath, ".")] = SourcePos{Line: lineAt(keyStart), EndLine: lineAt(decoder.InputOffset() - 1)}
+				}
+			}
+			_, err = decoder.Token()
+			return true, err
+		case json.Delim('['):
+			// blocks may also be given as arrays of objects
+			for decoder.More() {
+				if _, err := walk(path); nil != err {
+					return false, err
+				}
+			}
+			_, err = decoder.Token()
+			return false, err
+		}
+		return false, nil
+	}
+
+	_, err := walk(nil)
+	if nil != err {
+		return nil, fmt.Errorf("reading json positions: %v", err)
+	}
+	return positions, nil
+}
+
+func skipJSONSpace(content []byte, offset int64) int64 {
+	for offset < int64(len(content)) && strings.IndexByte(" \t\r\n,", content[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
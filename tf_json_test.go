@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJSONModuleFiles(t *testing.T) {
+	Convey("Json syntax files must load like native ones", t, func() {
+		state := loadTestState("testdata/json")
+
+		root := state.allModulesMap["."]
+		So(root, ShouldNotBeNil)
+		So(len(root.Inputs), ShouldEqual, 2)
+		So(root.Inputs[1].Name, ShouldEqual, "instance_count")
+		So(root.Inputs[1].Required, ShouldBeFalse)
+		So(root.Inputs[1].Description, ShouldEqual, "number of instances")
+		So(root.Inputs[1].Pos, ShouldResemble, SourcePos{Filename: "testdata/json/main.tf.json", Line: 5, EndLine: 8})
+
+		So(len(root.ModuleInstances), ShouldEqual, 1)
+		So(root.ModuleInstances[0].ModulePath, ShouldEqual, "modules.app")
+		So(root.ModuleInstances[0].Pos.Line, ShouldEqual, 11)
+		So(len(root.Inputs[0].AsModuleInput), ShouldEqual, 1)
+		So(root.Inputs[0].AsModuleInput[0].UsagePath, ShouldResemble, [][]string{{"app", "image"}})
+
+		So(len(root.Outputs), ShouldEqual, 1)
+		So(len(root.Outputs[0].FromModuleOutput), ShouldEqual, 1)
+
+		app := state.allModulesMap["modules.app"]
+		So(len(app.Resources), ShouldEqual, 3)
+		So(app.Resources[1].Type, ShouldEqual, "aws_ebs_volume")
+		So(app.Resources[1].Pos.Line, ShouldEqual, 9)
+		So(app.Resources[1].Pos.EndLine, ShouldEqual, 11)
+		So(state.allInputsMap["modules.app.size"].AsArgument[0].UsagePath, ShouldResemble, [][]string{{"aws_ebs_volume", "data", "size"}, {"aws_instance", "web", "count"}})
+	})
+}
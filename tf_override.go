@@ -0,0 +1,235 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/hashicorp/hcl/hcl/ast"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// override files (override.tf, *_override.tf and their .tf.json forms) are merged into the blocks they override
+type moduleFileAST struct {
+	Path string
+	File *ast.File
+	// files failing to parse are kept to be reported
+	Err error
+}
+
+func isOverrideFileName(filePath string) bool {
+	name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(filePath), ".json"), ".tf")
+	return "override" == name || strings.HasSuffix(name, "_override")
+}
+
+// returns files of the module with override files merged in and left out, parsed files are not modified
+func applyOverrideFiles(files []moduleFileAST) []moduleFileAST {
+	var result, overrides []moduleFileAST
+	for _, file := range files {
+		if isOverrideFileName(file.Path) {
+			overrides = append(overrides, file)
+			continue
+		}
+		// merged items are replaced in a copy of the top level list
+		objects, ok := file.File.Node.(*ast.ObjectList)
+		if !ok {
+			result = append(result, file)
+			continue
+		}
+		copied := &ast.File{Node: &ast.ObjectList{Items: append([]*ast.ObjectItem{}, objects.Items...)}, Comments: file.File.Comments}
+		result = append(result, moduleFileAST{Path: file.Path, File: copied})
+	}
+
+	// override files are applied in lexical order, as they were discovered
+	for _, override := range overrides {
+		objects, ok := override.File.Node.(*ast.ObjectList)
+		if !ok {
+			continue
+		}
+		for _, item := range objects.Items {
+			for _, name := range mergeOverrideItem(result, item) {
+				log.Warningf("override file %s: %s has no base block to override (SKIPPED)", override.Path, name)
+			}
+		}
+	}
+	return result
+}
+
+func objectItemName(item *ast.ObjectItem) string {
+	var keys []string
+	for _, key := range item.Keys {
+		keys = append(keys, objectKeyText(key))
+	}
+	return strings.Join(keys, ".")
+}
+
+// replaces the first block with the same type and labels by its merged copy, returns names of what was not merged
+func mergeOverrideItem(files []moduleFileAST, override *ast.ObjectItem) []string {
+	name := objectItemName(override)
+	switch name {
+	case "locals":
+		return mergeOverrideLocals(files, override)
+	case "terraform":
+		return mergeOverrideTerraform(files, override)
+	}
+	if !mergeIntoBlock(files, name, func(*ast.ObjectItem) bool { return true }, override) {
+		return []string{name}
+	}
+	return nil
+}
+
+// merges the override into the first block with the name accepted by match
+func mergeIntoBlock(files []moduleFileAST, name string, match func(*ast.ObjectItem) bool, override *ast.ObjectItem) bool {
+	for _, file := range files {
+		objects := file.File.Node.(*ast.ObjectList)
+		for i, item := range objects.Items {
+			if objectItemName(item) == name && match(item) {
+				objects.Items[i] = mergeBlock(item, override, false)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// every local replaces its definition in whichever locals block declares it
+func mergeOverrideLocals(files []moduleFileAST, override *ast.ObjectItem) []string {
+	value, ok := override.Val.(*ast.ObjectType)
+	if !ok || nil == value.List {
+		return nil
+	}
+	var missing []string
+	for _, local := range value.List.Items {
+		name := unquote(objectKeyText(local.Keys[0]))
+		declares := func(item *ast.ObjectItem) bool { return objectHasKey(item, name) }
+		if !mergeIntoBlock(files, "locals", declares, withObjectItems(override, []*ast.ObjectItem{local})) {
+			missing = append(missing, "locals."+name)
+		}
+	}
+	return missing
+}
+
+// provider requirements replace the requirement in whichever terraform block declares the provider,
+// the rest of the override is merged into the first terraform block
+func mergeOverrideTerraform(files []moduleFileAST, override *ast.ObjectItem) []string {
+	value, ok := override.Val.(*ast.ObjectType)
+	if !ok || nil == value.List {
+		return nil
+	}
+	var rest []*ast.ObjectItem
+	for _, item := range value.List.Items {
+		requirements, isBlock := item.Val.(*ast.ObjectType)
+		if "required_providers" != objectKeyText(item.Keys[0]) || !isBlock || nil == requirements.List {
+			rest = append(rest, item)
+			continue
+		}
+		var undeclared []*ast.ObjectItem
+		for _, requirement := range requirements.List.Items {
+			provider := unquote(objectKeyText(requirement.Keys[0]))
+			requires := func(block *ast.ObjectItem) bool { return requiresProvider(block, provider) }
+			single := withObjectItems(override, []*ast.ObjectItem{withObjectItems(item, []*ast.ObjectItem{requirement})})
+			if !mergeIntoBlock(files, "terraform", requires, single) {
+				undeclared = append(undeclared, requirement)
+			}
+		}
+		if 0 != len(undeclared) {
+			rest = append(rest, withObjectItems(item, undeclared))
+		}
+	}
+
+	if 0 != len(rest) && !mergeIntoBlock(files, "terraform", func(*ast.ObjectItem) bool { return true }, withObjectItems(override, rest)) {
+		return []string{"terraform"}
+	}
+	return nil
+}
+
+func requiresProvider(block *ast.ObjectItem, provider string) bool {
+	value, ok := block.Val.(*ast.ObjectType)
+	if !ok || nil == value.List {
+		return false
+	}
+	for _, item := range value.List.Filter("required_providers").Items {
+		if objectHasKey(item, provider) {
+			return true
+		}
+	}
+	return false
+}
+
+// copy of the block with other items
+func withObjectItems(block *ast.ObjectItem, items []*ast.ObjectItem) *ast.ObjectItem {
+	copied := *block
+	value := block.Val.(*ast.ObjectType)
+	copied.Val = &ast.ObjectType{Lbrace: value.Lbrace, Rbrace: value.Rbrace, List: &ast.ObjectList{Items: items}}
+	return &copied
+}
+
+// arguments of the override replace arguments with the same name, nested blocks replace all nested blocks of the same type
+// except lifecycle of resources and required_providers of terraform, which are merged by argument
+func mergeBlock(base *ast.ObjectItem, override *ast.ObjectItem, mergeNested bool) *ast.ObjectItem {
+	baseValue, baseOk := base.Val.(*ast.ObjectType)
+	overrideValue, overrideOk := override.Val.(*ast.ObjectType)
+	if !baseOk || !overrideOk || nil == overrideValue.List {
+		return base
+	}
+
+	var items []*ast.ObjectItem
+	if nil != baseValue.List {
+		items = append(items, baseValue.List.Items...)
+	}
+	isResource := "resource" == objectKeyText(base.Keys[0]) || "data" == objectKeyText(base.Keys[0])
+	isTerraform := "terraform" == objectKeyText(base.Keys[0])
+
+	replacedBlocks := make(map[string]bool)
+	for _, overrideItem := range overrideValue.List.Items {
+		name := objectKeyText(overrideItem.Keys[0])
+		_, isBlock := overrideItem.Val.(*ast.ObjectType)
+
+		switch {
+		case isBlock && ((isResource && "lifecycle" == name) || (isTerraform && "required_providers" == name)):
+			merged := false
+			for i, item := range items {
+				if objectKeyText(item.Keys[0]) == name {
+					items[i] = mergeBlock(item, overrideItem, true)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				items = append(items, overrideItem)
+			}
+		case isBlock && !mergeNested:
+			if !replacedBlocks[name] {
+				items = removeObjectItems(items, name)
+				replacedBlocks[name] = true
+			}
+			items = append(items, overrideItem)
+		default:
+			replaced := false
+			for i, item := range items {
+				if objectKeyText(item.Keys[0]) == name {
+					items[i] = overrideItem
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				items = append(items, overrideItem)
+			}
+		}
+	}
+
+	merged := *base
+	merged.Val = &ast.ObjectType{Lbrace: baseValue.Lbrace, Rbrace: baseValue.Rbrace, List: &ast.ObjectList{Items: items}}
+	return &merged
+}
+
+func removeObjectItems(items []*ast.ObjectItem, name string) []*ast.ObjectItem {
+	result := items[:0:0]
+	for _, item := range items {
+		if objectKeyText(item.Keys[0]) != name {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/hcl/ast"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOverrideFiles(t *testing.T) {
+	Convey("Override files must be merged into the blocks they override", t, func() {
+		So(isOverrideFileName("override.tf"), ShouldBeTrue)
+		So(isOverrideFileName("dev_override.tf.json"), ShouldBeTrue)
+		So(isOverrideFileName("overrides.tf"), ShouldBeFalse)
+
+		state := loadTestState("testdata/override")
+		root := state.allModulesMap["."]
+		So(len(root.Resources), ShouldEqual, 1)
+		So(root.Resources[0].Pos.Filename, ShouldEqual, "testdata/override/main.tf")
+
+		So(len(state.allInputsMap[".ami"].AsArgument), ShouldEqual, 0)
+		So(state.allInputsMap[".patched_ami"].AsArgument[0].UsagePath, ShouldResemble, [][]string{{"aws_instance", "web", "ami"}})
+		So(state.allInputsMap[".size"].AsArgument[0].UsagePath, ShouldResemble, [][]string{{"aws_instance", "web", "count"}})
+		So(state.allInputsMap[".size"].Default, ShouldEqual, "2")
+		So(state.allInputsMap[".size"].Pos.Filename, ShouldEqual, "testdata/override/main.tf")
+	})
+
+	Convey("Locals and provider requirements must be overridden one by one", t, func() {
+		state := loadTestState("testdata/override")
+		root := state.allModulesMap["."]
+
+		locals := make(map[string]string)
+		for _, local := range root.Locals {
+			locals[local.Name] = local.Value
+		}
+		So(locals, ShouldResemble, map[string]string{"a": `"1"`, "b": `"${var.y}"`})
+
+		So(root.Terraform.RequiredVersion, ShouldEqual, ">= 0.12")
+		So(root.Terraform.RequiredProviders, ShouldResemble, map[string]ProviderRequirement{
+			"aws":    {Source: "hashicorp/aws", Version: "~> 4.0"},
+			"random": {Version: "~> 2.0"},
+		})
+	})
+
+	Convey("Lifecycle of resources must be merged by argument", t, func() {
+		files := []moduleFileAST{}
+		for _, path := range []string{"testdata/override/main.tf", "testdata/override/web_override.tf"} {
+			file, err := parseModuleFile(path)
+			So(err, ShouldBeNil)
+			files = append(files, moduleFileAST{Path: path, File: file})
+		}
+
+		merged := applyOverrideFiles(files)
+		So(len(merged), ShouldEqual, 1)
+		web := merged[0].File.Node.(*ast.ObjectList).Filter("resource", "aws_instance", "web").Items[0]
+		lifecycle := web.Val.(*ast.ObjectType).List.Filter("lifecycle").Items
+		So(len(lifecycle), ShouldEqual, 1)
+		So(len(lifecycle[0].Val.(*ast.ObjectType).List.Items), ShouldEqual, 2)
+
+		// parsed files are left intact
+		original := files[0].File.Node.(*ast.ObjectList).Filter("resource", "aws_instance", "web").Items[0]
+		So(len(original.Val.(*ast.ObjectType).List.Filter("lifecycle").Items[0].Val.(*ast.ObjectType).List.Items), ShouldEqual, 1)
+	})
+}
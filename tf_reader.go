@@ -1,73 +1,256 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/hcl/hcl/printer"
 )
 
 //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 // file loading
 func loadModule(terraformRoot string, moduleRoot string, awsResources []Resource, state *HierarchyState) error {
-	modulePath := filepath.Join(terraformRoot, moduleRoot)
-	log.Info("loading module: ", modulePath)
+	log.Info("loading module: ", filepath.Join(terraformRoot, moduleRoot))
+
+	moduleDirs, err := discoverModules(terraformRoot, moduleRoot)
+	if err != nil {
+		return err
+	}
+
+	var paths []string
+	for _, moduleDir := range moduleDirs {
+		for _, file := range moduleDir.Files {
+			paths = append(paths, file.Path)
+		}
+	}
+	parsedFiles := parseModuleFiles(paths, *jobs)
+
+	// files are applied in discovery order, so the result does not depend on parsing order
+	for _, moduleDir := range moduleDirs {
+		var files []moduleFileAST
+		for _, file := range moduleDir.Files {
+			parsed := parsedFiles[file.Path]
+			files = append(files, moduleFileAST{Path: file.Path, File: parsed.File, Err: parsed.Err})
+		}
+
+		terragruntFile := ""
+		if nil != moduleDir.Terragrunt {
+			terragruntFile = moduleDir.Terragrunt.Path
+		}
+		buildModule(state.NewModule(getModuleName(terraformRoot, moduleDir.Root)), terraformRoot, moduleDir.Root, files, terragruntFile, awsResources, state)
+	}
+	return nil
+}
+
+// fills the module from its parsed files with override files merged in and its terragrunt.hcl if any,
+// files failing to parse are skipped and recorded as load errors of the module
+func buildModule(module *Module, terraformRoot string, moduleRoot string, files []moduleFileAST, terragruntFile string, awsResources []Resource, state *HierarchyState) {
+	module.Path = moduleRoot
+	module.IsLoaded = true
+
+	parsed := make([]moduleFileAST, 0, len(files))
+	for _, file := range files {
+		if nil != file.Err {
+			log.Errorf("error reading file '%s' (SKIPPED): %v", file.Path, file.Err)
+			module.NewLoadError(parseErrorPos(file.Path, file.Err), file.Err.Error())
+			continue
+		}
+		parsed = append(parsed, file)
+	}
+
+	for _, file := range applyOverrideFiles(parsed) {
+		_, err := processModuleFile(module, file.Path, file.File, awsResources, state)
+		if err != nil {
+			log.Errorf("error reading file '%s' (SKIPPED): %v", file.Path, err)
+		}
+	}
 
-	module := state.NewModule(getModuleName(terraformRoot, moduleRoot))
+	if "" != terragruntFile {
+		if err := processTerragruntFile(module, terraformRoot, terragruntFile, state); nil != err {
+			log.Errorf("error reading file '%s' (SKIPPED): %v", terragruntFile, err)
+			module.NewLoadError(parseErrorPos(terragruntFile, err), err.Error())
+		}
+	}
+}
+
+var parseErrorLineRegexp = regexp.MustCompile("(?:At |line )(\\d+)")
+
+// line of the parse error if the message tells it, the first line otherwise
+func parseErrorPos(filePath string, err error) SourcePos {
+	pos := SourcePos{Filename: filePath, Line: 1, EndLine: 1}
+	if match := parseErrorLineRegexp.FindStringSubmatch(err.Error()); nil != match {
+		pos.Line, _ = strconv.Atoi(match[1])
+		pos.EndLine = pos.Line
+	}
+	return pos
+}
+
+type moduleDirFile struct {
+	Path string
+	Info os.FileInfo
+}
+
+type moduleDir struct {
+	// relative to terraform root
+	Root  string
+	Files []moduleDirFile
+	// terragrunt.hcl of the directory if any
+	Terragrunt *moduleDirFile
+}
+
+// every directory is a module, parents go before their subdirectories
+func discoverModules(terraformRoot string, moduleRoot string) ([]moduleDir, error) {
+	return discoverFilteredModules(terraformRoot, moduleRoot, newPathFilter(terraformRoot))
+}
+
+func discoverFilteredModules(terraformRoot string, moduleRoot string, filter *pathFilter) ([]moduleDir, error) {
+	modulePath := filepath.Join(terraformRoot, moduleRoot)
 
 	files, err := ioutil.ReadDir(modulePath)
 	if err != nil {
-		return fmt.Errorf("error reading directory: ", err)
+		return nil, fmt.Errorf("error reading directory: %v", err)
 	}
 
+	var result []moduleDir
+	current := moduleDir{Root: moduleRoot}
+	var subdirs []string
 	for _, file := range files {
+		relPath := filepath.Join(moduleRoot, file.Name())
+		if filter.IsExcluded(relPath) {
+			log.Debug("excluded: ", relPath)
+			continue
+		}
 
 		if file.IsDir() {
-			log.Info("load module: ", filepath.Join(moduleRoot, file.Name()))
-			err := loadModule(*rootDir, filepath.Join(moduleRoot, file.Name()), awsResources, state)
-
-			if err != nil {
-				log.Errorf("error reading file '%s' (SKIPPED): %v", file.Name(), err)
+			if nil != moduleParseCache && moduleParseCache.IsCacheDir(filepath.Join(modulePath, file.Name())) {
+				continue
 			}
-		} else {
-			moduleFile := filepath.Join(modulePath, file.Name())
+			subdirs = append(subdirs, relPath)
+			continue
+		}
+
+		moduleFile := filepath.Join(modulePath, file.Name())
+		if terragruntFileName == file.Name() {
+			current.Terragrunt = &moduleDirFile{Path: moduleFile, Info: file}
+		}
+		if isModuleFileName(moduleFile) {
 			log.Debug("moduleFile = ", moduleFile)
-			_, err = loadModuleFile(module, moduleFile, awsResources, state)
-			if err != nil {
-				log.Errorf("error reading file '%s' (SKIPPED): %v", moduleFile, err)
-			}
+			current.Files = append(current.Files, moduleDirFile{Path: moduleFile, Info: file})
 		}
 	}
-	return nil
+	if filter.IsIncluded(moduleRoot) {
+		result = append(result, current)
+	}
+
+	for _, subdir := range subdirs {
+		log.Info("load module: ", subdir)
+		subdirModules, err := discoverFilteredModules(terraformRoot, subdir, filter)
+		if err != nil {
+			log.Errorf("error reading file '%s' (SKIPPED): %v", subdir, err)
+			continue
+		}
+		result = append(result, subdirModules...)
+	}
+	return result, nil
 }
 
-func loadModuleFile(module *Module, filePath string, awsResources []Resource, state *HierarchyState) (*HierarchyState, error) {
-	re := regexp.MustCompile(".*\\.tf")
-	if !re.MatchString(filePath) {
-		return state, nil
+type parsedModuleFile struct {
+	File *ast.File
+	Err  error
+}
+
+// parses files with a pool of workers
+func parseModuleFiles(paths []string, workers int) map[string]parsedModuleFile {
+	if workers < 1 {
+		workers = 1
 	}
 
-	log.Info("module file loading: ", filePath)
+	results := make([]parsedModuleFile, len(paths))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				log.Info("module file loading: ", paths[index])
+				file, err := parseModuleFile(paths[index])
+				results[index] = parsedModuleFile{File: file, Err: err}
+			}
+		}()
+	}
+	for i := range paths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
 
+	if nil != moduleParseCache {
+		hits, misses := moduleParseCache.Stats()
+		log.Debugf("parse cache: %d hits, %d misses", hits, misses)
+	}
+
+	parsed := make(map[string]parsedModuleFile, len(paths))
+	for i, path := range paths {
+		parsed[path] = results[i]
+	}
+	return parsed
+}
+
+// terraform configuration files, either native syntax or json
+func isModuleFileName(filePath string) bool {
+	return strings.HasSuffix(filePath, ".tf") || strings.HasSuffix(filePath, ".tf.json")
+}
+
+func parseModuleFile(filePath string) (*ast.File, error) {
 	bytes, err := ioutil.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("module file loading (%s): %v", filePath, err)
 	}
 
-	hclFile, err := hcl.Parse(string(bytes))
+	syntax := "hcl"
+	if isJSONModuleFileName(filePath) {
+		syntax = "json"
+	}
+
+	if nil != moduleParseCache {
+		if hclFile, found := moduleParseCache.Get(syntax, bytes); found {
+			return hclFile, nil
+		}
+	}
+
+	var hclFile *ast.File
+	if "json" == syntax {
+		hclFile, err = parseJSONModuleFile(bytes)
+	} else {
+		hclFile, err = hcl.Parse(string(bytes))
+	}
 	if err != nil {
-		return nil, fmt.Errorf("module file loading (%s): unmarshalling from hcl: %v", filePath, err)
+		return nil, fmt.Errorf("module file loading (%s): unmarshalling from %s: %v", filePath, syntax, err)
 	}
 
+	if nil != moduleParseCache {
+		moduleParseCache.Put(syntax, bytes, hclFile)
+	}
+	return hclFile, nil
+}
+
+func processModuleFile(module *Module, filePath string, hclFile *ast.File, awsResources []Resource, state *HierarchyState) (*HierarchyState, error) {
 	objects := hclFile.Node.(*ast.ObjectList)
 
 	for _, objItem := range objects.Items {
-		_, err = processModuleObject(module, objItem, awsResources, state)
+		_, err := processModuleObject(module, filePath, objItem, awsResources, state)
 		if nil != err {
 			log.Warningf("module file loading (%s): error processing module object: %v", filePath, err)
 		}
@@ -80,29 +263,64 @@ func loadModuleFile(module *Module, filePath string, awsResources []Resource, st
 
 //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 // process one of file root objects
-func processModuleObject(module *Module, object *ast.ObjectItem, awsResources []Resource, state *HierarchyState) (*HierarchyState, error) {
+func processModuleObject(module *Module, filePath string, object *ast.ObjectItem, awsResources []Resource, state *HierarchyState) (*HierarchyState, error) {
 	var strKeys []string
 
 	for _, key := range object.Keys {
-		strKeys = append(strKeys, key.Token.Text)
+		strKeys = append(strKeys, objectKeyText(key))
 	}
 
+	if 1 == len(strKeys) && "//" == strKeys[0] {
+		// comment of json syntax
+		return state, nil
+	}
+	if 1 == len(strKeys) && "terraform" == strKeys[0] {
+		processTerraform(module, objectPos(filePath, object), object)
+		return state, nil
+	}
+	if 1 == len(strKeys) && "locals" == strKeys[0] {
+		processLocals(module, filePath, object)
+		return state, nil
+	}
 	if len(strKeys) < 2 {
 		return nil, fmt.Errorf("process module object: wrong number of object keys (expected at least 2)")
 	}
 
+	pos := objectPos(filePath, object)
+
 	switch strKeys[0] {
 	case "variable":
 		moduleInput := state.NewInput(module, VariableID(unquote(strKeys[1])))
 		moduleInput.IsLoaded = true
+		moduleInput.Pos = pos
+		moduleInput.Required = !objectHasKey(object, "default")
+		moduleInput.Type = unquote(objectValueText(object, "type"))
+		moduleInput.Default = objectValueText(object, "default")
+		moduleInput.Description = unquote(objectValueText(object, "description"))
+		moduleInput.Sensitive = "true" == unquote(objectValueText(object, "sensitive"))
 	case "output":
 		moduleOutput := state.NewOutput(module, VariableID(unquote(strKeys[1])))
 		moduleOutput.IsLoaded = true
+		moduleOutput.Pos = pos
+		moduleOutput.Description = unquote(objectValueText(object, "description"))
+		moduleOutput.Sensitive = "true" == unquote(objectValueText(object, "sensitive"))
 		processOutput(module, object.Val.(*ast.ObjectType), Map(strKeys[1:], unquote), awsResources, state)
 	case "resource":
+		if len(strKeys) > 2 {
+			module.NewResource(unquote(strKeys[1]), unquote(strKeys[2]), unquote(objectValueText(object, "provider")), pos)
+		}
 		processResource(module, object.Val.(*ast.ObjectType), Map(strKeys[1:], unquote), awsResources, state)
 	case "module":
-		processModule(module, object.Val.(*ast.ObjectType), Map(strKeys[1:], unquote), awsResources, state)
+		processModule(module, pos, object.Val.(*ast.ObjectType), Map(strKeys[1:], unquote), awsResources, state)
+	case "provider":
+		processProvider(module, pos, object, unquote(strKeys[1]))
+	case "data":
+		if len(strKeys) > 2 && "terraform_remote_state" == unquote(strKeys[1]) {
+			processRemoteState(module, pos, object, unquote(strKeys[2]))
+		} else if value, ok := object.Val.(*ast.ObjectType); ok && len(strKeys) > 2 {
+			// variables passed to data sources are used as arguments of data.<type>.<name>
+			processResource(module, value, []string{"data." + unquote(strKeys[1]), unquote(strKeys[2])}, awsResources, state)
+		}
 	default:
 		log.Warning("process module object: unknown item type: ", strKeys[0])
 	}
@@ -110,6 +328,152 @@ func processModuleObject(module *Module, object *ast.ObjectItem, awsResources []
 	return state, nil
 }
 
+func processProvider(module *Module, pos SourcePos, object *ast.ObjectItem, name string) {
+	provider := ModuleProvider{Name: name, Alias: unquote(objectValueText(object, "alias")), Pos: pos, Arguments: make(map[string]string)}
+	if value, ok := object.Val.(*ast.ObjectType); ok && nil != value.List {
+		for _, item := range value.List.Items {
+			key := unquote(objectKeyText(item.Keys[0]))
+			if "alias" != key {
+				provider.Arguments[key] = unquote(objectValueText(object, key))
+			}
+		}
+	}
+	module.NewProvider(provider)
+}
+
+func processRemoteState(module *Module, pos SourcePos, object *ast.ObjectItem, name string) {
+	remoteState := module.NewRemoteState(name)
+	remoteState.IsLoaded = true
+	remoteState.Pos = pos
+	remoteState.Backend = unquote(objectValueText(object, "backend"))
+
+	value, ok := object.Val.(*ast.ObjectType)
+	if !ok || nil == value.List {
+		return
+	}
+	for _, item := range value.List.Filter("config").Items {
+		// both config = { ... } and config { ... }
+		if config, ok := item.Val.(*ast.ObjectType); ok && nil != config.List {
+			readBackendConfig(config.List, "", remoteState.Config)
+		}
+	}
+}
+
+// literal arguments of backend configs, arguments of nested blocks are prefixed by the block name (workspaces.name)
+func readBackendConfig(list *ast.ObjectList, prefix string, config map[string]string) {
+	for _, argument := range list.Items {
+		key := prefix + unquote(objectKeyText(argument.Keys[0]))
+		switch value := argument.Val.(type) {
+		case *ast.LiteralType:
+			config[key] = unquote(value.Token.Text)
+		case *ast.ObjectType:
+			if nil != value.List {
+				readBackendConfig(value.List, key+".", config)
+			}
+		}
+	}
+}
+
+// several terraform blocks of a module are merged
+func processTerraform(module *Module, pos SourcePos, object *ast.ObjectItem) {
+	if nil == module.Terraform {
+		module.Terraform = &ModuleSettings{Pos: pos, RequiredProviders: make(map[string]ProviderRequirement)}
+	}
+	settings := module.Terraform
+
+	value, ok := object.Val.(*ast.ObjectType)
+	if !ok || nil == value.List {
+		return
+	}
+	for _, item := range value.List.Items {
+		switch unquote(objectKeyText(item.Keys[0])) {
+		case "required_version":
+			settings.RequiredVersion = unquote(objectValueText(object, "required_version"))
+		case "required_providers":
+			requirements, ok := item.Val.(*ast.ObjectType)
+			if !ok || nil == requirements.List {
+				continue
+			}
+			for _, requirement := range requirements.List.Items {
+				name := unquote(objectKeyText(requirement.Keys[0]))
+				switch requirementValue := requirement.Val.(type) {
+				case *ast.LiteralType:
+					// version only, terraform 0.12 and older
+					settings.RequiredProviders[name] = ProviderRequirement{Version: unquote(requirementValue.Token.Text)}
+				case *ast.ObjectType:
+					settings.RequiredProviders[name] = ProviderRequirement{
+						Source:  unquote(objectValueText(requirement, "source")),
+						Version: unquote(objectValueText(requirement, "version")),
+					}
+				}
+			}
+		case "backend":
+			if 2 != len(item.Keys) {
+				continue
+			}
+			settings.Backend = unquote(objectKeyText(item.Keys[1]))
+			settings.BackendConfig = make(map[string]string)
+			if config, ok := item.Val.(*ast.ObjectType); ok && nil != config.List {
+				readBackendConfig(config.List, "", settings.BackendConfig)
+			}
+		}
+	}
+}
+
+// several locals blocks of a module are merged, values of any type are kept as hcl text
+func processLocals(module *Module, filePath string, object *ast.ObjectItem) {
+	value, ok := object.Val.(*ast.ObjectType)
+	if !ok || nil == value.List {
+		return
+	}
+	for _, item := range value.List.Items {
+		name := unquote(objectKeyText(item.Keys[0]))
+		local := module.NewLocal(name)
+		local.IsLoaded = true
+		local.Pos = SourcePos{Filename: filePath, Line: item.Pos().Line, EndLine: item.Pos().Line}
+		local.Value = objectValueText(object, name)
+		findLocalUsages(local.Value, module, "local", []string{name})
+	}
+}
+
+func objectHasKey(object *ast.ObjectItem, key string) bool {
+	if value, ok := object.Val.(*ast.ObjectType); ok && nil != value.List {
+		return len(value.List.Filter(key).Items) > 0
+	}
+	return false
+}
+
+// literal values are returned as is, complex ones are printed back to hcl
+func objectValueText(object *ast.ObjectItem, key string) string {
+	value, ok := object.Val.(*ast.ObjectType)
+	if !ok || nil == value.List {
+		return ""
+	}
+	items := value.List.Filter(key).Items
+	if 0 == len(items) {
+		return ""
+	}
+
+	if literal, ok := items[0].Val.(*ast.LiteralType); ok {
+		return literal.Token.Text
+	}
+
+	var buffer bytes.Buffer
+	if err := printer.Fprint(&buffer, items[0].Val); nil != err {
+		log.Warningf("printing value of '%s': %v", key, err)
+		return ""
+	}
+	return buffer.String()
+}
+
+func objectPos(filePath string, object *ast.ObjectItem) SourcePos {
+	pos := SourcePos{Filename: filePath, Line: object.Pos().Line, EndLine: object.Pos().Line}
+	if value, ok := object.Val.(*ast.ObjectType); ok {
+		pos.EndLine = value.Rbrace.Line
+	}
+	return pos
+}
+
 //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 // process resource
 func processResource(module *Module, object *ast.ObjectType, resourceName []string, awsResources []Resource, state *HierarchyState) {
@@ -121,20 +485,56 @@ func processResource(module *Module, object *ast.ObjectType, resourceName []stri
 			}
 			fieldResourceName := resourceName
 			for _, k := range i.Keys {
-				fieldResourceName = append(fieldResourceName, k.Token.Text)
+				fieldResourceName = append(fieldResourceName, objectKeyText(k))
 			}
 
-			switch value := i.Val.(type) {
-			case *ast.LiteralType:
-				findInputVariableAsArgumentUsages(value.Token.Text, module, fieldResourceName, awsResources, state)
-				//findModuleOutputUsages(value.Token.Text, module, fieldResourceName, awsResources, state)
-			default:
-				log.Warningf("process resource: unsupported value type for resourceName: %v value: %+v", fieldResourceName, value)
+			// values nested in maps and blocks (tags, ingress) are used by the top level argument
+			tokens, supported := literalTokens(i.Val)
+			if !supported {
+				log.Warningf("process resource: unsupported value type for resourceName: %v value: %+v", fieldResourceName, i.Val)
+				continue
+			}
+			for _, token := range tokens {
+				findInputVariableAsArgumentUsages(token, module, fieldResourceName, awsResources, state)
+				findRemoteStateUsages(token, module, "argument", fieldResourceName)
+				findLocalUsages(token, module, "argument", fieldResourceName)
+				//findModuleOutputUsages(token, module, fieldResourceName, awsResources, state)
 			}
 		}
 	}
 }
 
+// literal values of the node, objects and lists are walked down
+func literalTokens(node ast.Node) ([]string, bool) {
+	switch value := node.(type) {
+	case *ast.LiteralType:
+		return []string{value.Token.Text}, true
+	case *ast.ObjectType:
+		var tokens []string
+		if nil != value.List {
+			for _, item := range value.List.Items {
+				itemTokens, supported := literalTokens(item.Val)
+				if !supported {
+					return nil, false
+				}
+				tokens = append(tokens, itemTokens...)
+			}
+		}
+		return tokens, true
+	case *ast.ListType:
+		var tokens []string
+		for _, item := range value.List {
+			itemTokens, supported := literalTokens(item)
+			if !supported {
+				return nil, false
+			}
+			tokens = append(tokens, itemTokens...)
+		}
+		return tokens, true
+	}
+	return nil, false
+}
+
 func findModuleOutputUsages(token string, module *Module, fieldResourceName []string, awsResources []Resource, state *HierarchyState) {
 	log.Fatal("NOT IMPLEMENTED!")
 }
@@ -153,11 +553,20 @@ func findInputVariableAsArgumentUsages(token string, module *Module, fieldResour
 
 //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 // process module instance
-func processModule(module *Module, object *ast.ObjectType, resourceName []string, awsResources []Resource, state *HierarchyState) {
+func processModule(module *Module, pos SourcePos, object *ast.ObjectType, resourceName []string, awsResources []Resource, state *HierarchyState) {
 	instanceName := resourceName[0]
 
 	log.Info("Processing module instance: ", instanceName)
 	if nil != object.List && nil != object.List.Items {
+		// instance must be registered before its arguments reference it
+		for _, i := range object.List.Items {
+			if len(i.Keys) == 1 && "source" == objectKeyText(i.Keys[0]) {
+				if value, ok := i.Val.(*ast.LiteralType); ok {
+					registerInstance(value.Token.Text, module, instanceName, pos, state)
+				}
+			}
+		}
+
 		for _, i := range object.List.Items {
 			if len(i.Keys) != 1 {
 				log.Error("process resource: wrong number of keys, expected 1")
@@ -165,14 +574,21 @@ func processModule(module *Module, object *ast.ObjectType, resourceName []string
 			}
 			fieldResourceName := resourceName
 			for _, k := range i.Keys {
-				fieldResourceName = append(fieldResourceName, k.Token.Text)
+				fieldResourceName = append(fieldResourceName, objectKeyText(k))
 			}
 
 			switch value := i.Val.(type) {
 			case *ast.LiteralType:
-				registerInstance(value.Token.Text, module, fieldResourceName, instanceName, state)
 				findInputVariableModuleInputUsages(value.Token.Text, module, fieldResourceName, awsResources, state)
+				findRemoteStateUsages(value.Token.Text, module, "module-input", fieldResourceName)
+				findLocalUsages(value.Token.Text, module, "module-input", fieldResourceName)
 				//findModuleOutputUsages(value.Token.Text, module, fieldResourceName, awsResources, state)
+			case *ast.ObjectType:
+				if "providers" == fieldResourceName[1] {
+					processModuleProviders(module, instanceName, value)
+				} else {
+					log.Warningf("process resource: unsupported value type for resourceName: %v value: %+v", fieldResourceName, value)
+				}
 			default:
 				log.Warningf("process resource: unsupported value type for resourceName: %v value: %+v", fieldResourceName, value)
 			}
@@ -180,11 +596,34 @@ func processModule(module *Module, object *ast.ObjectType, resourceName []string
 	}
 }
 
-func registerInstance(token string, module *Module, fieldResourceName []string, instanceName string, state *HierarchyState) {
-	fieldName := fieldResourceName[1]
-	if fieldName == "source" {
-		module.NewInstance(instanceName, token, module)
+// providers = { "aws" = "aws.west" } passes provider configurations of the calling module
+func processModuleProviders(module *Module, instanceName string, object *ast.ObjectType) {
+	instance := module.FindModuleInstance(instanceName)
+	if nil == instance || nil == object.List {
+		log.Warningf("module instance %s: providers of unknown module instance", instanceName)
+		return
+	}
+
+	instance.Providers = make(map[string]string)
+	for _, item := range object.List.Items {
+		if value, ok := item.Val.(*ast.LiteralType); ok && 1 == len(item.Keys) {
+			instance.Providers[unquote(objectKeyText(item.Keys[0]))] = unquote(value.Token.Text)
+		}
+	}
+}
+
+func registerInstance(token string, module *Module, instanceName string, pos SourcePos, state *HierarchyState) {
+	source := unquote(token)
+	instance := state.NewModule(getSourceModuleName(module, source))
+	module.NewInstance(instanceName, source, instance, pos)
+}
+
+// local sources are resolved relative to the calling module, remote ones are kept as is
+func getSourceModuleName(module *Module, source string) string {
+	if strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") {
+		return getModuleName(*rootDir, filepath.Join(module.Path, source))
 	}
+	return source
 }
 
 func findInputVariableModuleInputUsages(token string, module *Module, fieldResourceName []string, awsResources []Resource, state *HierarchyState) {
@@ -213,12 +652,16 @@ func processOutput(module *Module, object *ast.ObjectType, resourceName []string
 			}
 			fieldResourceName := resourceName
 			for _, k := range i.Keys {
-				fieldResourceName = append(fieldResourceName, k.Token.Text)
+				fieldResourceName = append(fieldResourceName, objectKeyText(k))
 			}
 
 			switch value := i.Val.(type) {
 			case *ast.LiteralType:
 				findModuleOutputValues(value.Token.Text, module, fieldResourceName, awsResources, state)
+				if "value" == fieldResourceName[1] {
+					findRemoteStateUsages(value.Token.Text, module, "output", fieldResourceName)
+					findLocalUsages(value.Token.Text, module, "output", fieldResourceName)
+				}
 			default:
 				log.Warningf("process resource: unsupported value type for resourceName: %v value: %+v", fieldResourceName, value)
 			}
@@ -234,15 +677,23 @@ func findModuleOutputValues(token string, module *Module, fieldResourceName []st
 	resourceFields := findAllResourceFields(token)
 	moduleOutputName := VariableID(fieldResourceName[0])
 
+	for _, variable := range findAllVariables(token) {
+		state.ConnectOutputToInput(module, moduleOutputName, variable)
+	}
+
 	for _, resourceField := range resourceFields {
 		awsAttribute := getAttributeByName(resourceField.Name, resourceField.FieldName, awsResources)
-		state.ConnectOutputToAttribute(module, moduleOutputName, awsAttribute)
+		state.ConnectOutputToAttribute(module, moduleOutputName, resourceField, awsAttribute)
 	}
 
 	moduleFields := findAllModuleFields(token)
 	for _, moduleField := range moduleFields {
 		moduleInstance := module.FindModuleInstance(moduleField.InstanceName)
-		state.ConnectOutputToModuleOutput(moduleInstance, moduleOutputName, moduleField)
+		if nil == moduleInstance {
+			log.Warningf("module output %s: unknown module instance '%s'", moduleOutputName, moduleField.InstanceName)
+			continue
+		}
+		state.ConnectOutputToModuleOutput(module, moduleInstance, moduleOutputName, moduleField)
 	}
 }
 
@@ -277,6 +728,24 @@ func findAllResourceFields(token string) []ResourceFieldID {
 	return result
 }
 
+// data.terraform_remote_state.<name>.outputs.<output>, outputs. is omitted before terraform 0.12
+func findRemoteStateUsages(token string, module *Module, kind string, fieldResourceName []string) {
+	re := regexp.MustCompile("data\\.terraform_remote_state\\.([-a-zA-Z_0-9]*)\\.(?:outputs\\.)?([-a-zA-Z_0-9]*)")
+	for _, match := range re.FindAllStringSubmatch(token, -1) {
+		if "backend" == match[2] || "config" == match[2] {
+			continue
+		}
+		module.NewRemoteState(match[1]).AttachUsage(match[2], kind, fieldResourceName)
+	}
+}
+
+func findLocalUsages(token string, module *Module, kind string, fieldResourceName []string) {
+	re := regexp.MustCompile("local\\.([-a-zA-Z_0-9]*)")
+	for _, match := range re.FindAllStringSubmatch(token, -1) {
+		module.NewLocal(match[1]).AttachUsage(kind, fieldResourceName)
+	}
+}
+
 func findAllModuleFields(token string) []ModuleFieldID {
 	re := regexp.MustCompile("module\\.([-a-zA-Z_0-9]*)\\.([-a-zA-Z_0-9]*)")
 	matches := re.FindAllStringSubmatch(token, -1)
@@ -313,11 +782,12 @@ func getArgumentByName(resourceName string, fieldName string, awsResources []Res
 	s1 := unquote(resourceName)
 	s2 := unquote(fieldName)
 
-	for _, res := range awsResources {
-		if res.Name == s1 {
-			for _, arg := range res.Arguments {
-				if arg.Name == s2 {
-					return &arg
+	for i := range awsResources {
+		resource := &awsResources[i]
+		if resource.Name == s1 {
+			for j := range resource.Arguments {
+				if resource.Arguments[j].Name == s2 {
+					return &resource.Arguments[j]
 				}
 			}
 			break
@@ -330,11 +800,12 @@ func getAttributeByName(resourceName string, fieldName string, awsResources []Re
 	s1 := unquote(resourceName)
 	s2 := unquote(fieldName)
 
-	for _, res := range awsResources {
-		if res.Name == s1 {
-			for _, arg := range res.Attributes {
-				if arg.Name == s2 {
-					return &arg
+	for i := range awsResources {
+		resource := &awsResources[i]
+		if resource.Name == s1 {
+			for j := range resource.Attributes {
+				if resource.Attributes[j].Name == s2 {
+					return &resource.Attributes[j]
 				}
 			}
 			break
@@ -1,8 +1,10 @@
 package main
 
 import (
-	. "github.com/smartystreets/goconvey/convey"
+	"encoding/json"
 	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
 )
 
 func TestRegexUtils(t *testing.T) {
@@ -30,3 +32,22 @@ func TestRegexUtils(t *testing.T) {
 		So(result3[1].FieldName, ShouldEqual, "id")
 	})
 }
+
+func TestParallelParsing(t *testing.T) {
+	Convey("Loaded state must not depend on the number of parsing workers", t, func() {
+		defer func(workers int) { *jobs = workers }(*jobs)
+
+		for _, dir := range []string{"testdata/workspace", "testdata/terragrunt", "testdata/lint", "testdata/sensitive", "testdata/override", "testdata/json"} {
+			*jobs = 1
+			sequential, err := json.Marshal(loadTestState(dir))
+			So(err, ShouldBeNil)
+
+			for _, workers := range []int{2, 8, 64} {
+				*jobs = workers
+				parallel, err := json.Marshal(loadTestState(dir))
+				So(err, ShouldBeNil)
+				So(string(parallel), ShouldEqual, string(sequential))
+			}
+		}
+	})
+}
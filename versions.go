@@ -0,0 +1,366 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// version constraints, the subset of hashicorp/go-version syntax used in terraform configurations
+type version3 [3]int
+
+func parseVersion3(text string) (version3, int, error) {
+	var result version3
+	text = strings.TrimPrefix(strings.TrimSpace(text), "v")
+	// pre-release and metadata do not take part in comparison here
+	if index := strings.IndexAny(text, "-+"); index >= 0 {
+		text = text[:index]
+	}
+
+	parts := strings.Split(text, ".")
+	if len(parts) > 3 || "" == parts[0] {
+		return result, 0, fmt.Errorf("malformed version '%s'", text)
+	}
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if nil != err || number < 0 {
+			return result, 0, fmt.Errorf("malformed version '%s'", text)
+		}
+		result[i] = number
+	}
+	return result, len(parts), nil
+}
+
+func (v version3) Compare(other version3) int {
+	for i := range v {
+		if v[i] != other[i] {
+			if v[i] < other[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func (v version3) String() string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+type versionBound struct {
+	Version   version3
+	Inclusive bool
+	IsSet     bool
+}
+
+// intersection of constraints, versions between Lower and Upper except Excluded
+type versionRange struct {
+	Lower    versionBound
+	Upper    versionBound
+	Excluded []version3
+}
+
+func (r *versionRange) raiseLower(bound versionBound) {
+	if !r.Lower.IsSet || bound.Version.Compare(r.Lower.Version) > 0 || 0 == bound.Version.Compare(r.Lower.Version) && !bound.Inclusive {
+		r.Lower = bound
+	}
+}
+
+func (r *versionRange) lowerUpper(bound versionBound) {
+	if !r.Upper.IsSet || bound.Version.Compare(r.Upper.Version) < 0 || 0 == bound.Version.Compare(r.Upper.Version) && !bound.Inclusive {
+		r.Upper = bound
+	}
+}
+
+// adds a comma separated constraint like ">= 1.2, < 2.0" or "~> 4.0"
+func (r *versionRange) Add(constraint string) error {
+	for _, part := range strings.Split(constraint, ",") {
+		part = strings.TrimSpace(part)
+		if "" == part {
+			continue
+		}
+
+		operator := "="
+		for _, candidate := range []string{"~>", ">=", "<=", "!=", ">", "<", "="} {
+			if strings.HasPrefix(part, candidate) {
+				operator = candidate
+				part = strings.TrimSpace(part[len(candidate):])
+				break
+			}
+		}
+
+		version, precision, err := parseVersion3(part)
+		if nil != err {
+			return err
+		}
+
+		switch operator {
+		case "=":
+			r.raiseLower(versionBound{Version: version, Inclusive: true, IsSet: true})
+			r.lowerUpper(versionBound{Version: version, Inclusive: true, IsSet: true})
+		case "!=":
+			r.Excluded = append(r.Excluded, version)
+		case ">":
+			r.raiseLower(versionBound{Version: version, IsSet: true})
+		case ">=":
+			r.raiseLower(versionBound{Version: version, Inclusive: true, IsSet: true})
+		case "<":
+			r.lowerUpper(versionBound{Version: version, IsSet: true})
+		case "<=":
+			r.lowerUpper(versionBound{Version: version, Inclusive: true, IsSet: true})
+		case "~>":
+			// only the rightmost given part may increase: ~> 4.0 is >= 4.0, < 5.0 and ~> 4.0.1 is >= 4.0.1, < 4.1.0
+			upper := version
+			index := precision - 2
+			if index < 0 {
+				index = 0
+			}
+			upper[index]++
+			for i := index + 1; i < len(upper); i++ {
+				upper[i] = 0
+			}
+			r.raiseLower(versionBound{Version: version, Inclusive: true, IsSet: true})
+			r.lowerUpper(versionBound{Version: upper, IsSet: true})
+		}
+	}
+	return nil
+}
+
+func (r *versionRange) IsSatisfiable() bool {
+	if !r.Lower.IsSet || !r.Upper.IsSet {
+		return true
+	}
+	switch r.Lower.Version.Compare(r.Upper.Version) {
+	case 1:
+		return false
+	case 0:
+		if !r.Lower.Inclusive || !r.Upper.Inclusive {
+			return false
+		}
+		for _, excluded := range r.Excluded {
+			if 0 == excluded.Compare(r.Lower.Version) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (r *versionRange) String() string {
+	var parts []string
+	if r.Lower.IsSet {
+		operator := ">"
+		if r.Lower.Inclusive {
+			operator = ">="
+		}
+		parts = append(parts, operator+" "+r.Lower.Version.String())
+	}
+	if r.Upper.IsSet {
+		operator := "<"
+		if r.Upper.Inclusive {
+			operator = "<="
+		}
+		parts = append(parts, operator+" "+r.Upper.Version.String())
+	}
+	for _, excluded := range r.Excluded {
+		parts = append(parts, "!= "+excluded.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// constraints of a root combined from all reachable modules
+type VersionConstraint struct {
+	Module     string `form:"Module" json:"Module" xml:"Module"`
+	Constraint string `form:"Constraint" json:"Constraint" xml:"Constraint"`
+}
+
+type VersionRequirement struct {
+	// terraform or a provider name
+	Name        string              `form:"Name" json:"Name" xml:"Name"`
+	Constraints []VersionConstraint `form:"Constraints" json:"Constraints" xml:"Constraints"`
+	Effective   string              `form:"Effective" json:"Effective" xml:"Effective"`
+	Satisfiable bool                `form:"Satisfiable" json:"Satisfiable" xml:"Satisfiable"`
+	Errors      []string            `form:"Errors" json:"Errors,omitempty" xml:"Errors"`
+}
+
+type RootVersionReport struct {
+	Root         string               `form:"Root" json:"Root" xml:"Root"`
+	Path         string               `form:"Path" json:"Path" xml:"Path"`
+	Requirements []VersionRequirement `form:"Requirements" json:"Requirements" xml:"Requirements"`
+	// "<module>: required_version" or "<module>: <provider>" for providers used without a version constraint
+	MissingConstraints []string `form:"MissingConstraints" json:"MissingConstraints" xml:"MissingConstraints"`
+}
+
+func (r *RootVersionReport) IsSatisfiable() bool {
+	for _, requirement := range r.Requirements {
+		if !requirement.Satisfiable {
+			return false
+		}
+	}
+	return true
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// command
+func runVersions(args []string) error {
+	flags := flag.NewFlagSet("versions", flag.ExitOnError)
+	strict := flags.Bool("strict", false, "fail on modules missing version constraints too")
+	flags.Parse(args)
+
+	state, _, err := loadState()
+	if nil != err {
+		return err
+	}
+
+	reports := AnalyzeVersionConstraints(state)
+	jsonReport, err := json.Marshal(reports)
+	if nil != err {
+		return err
+	}
+	err = writeOutput(jsonReport)
+	if nil != err {
+		return err
+	}
+
+	for _, report := range reports {
+		if !report.IsSatisfiable() {
+			return fmt.Errorf("root %s has unsatisfiable version constraints", report.Path)
+		}
+		if *strict && 0 != len(report.MissingConstraints) {
+			return fmt.Errorf("root %s has modules without version constraints: %s", report.Path, strings.Join(report.MissingConstraints, ", "))
+		}
+	}
+	return nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// analysis
+func AnalyzeVersionConstraints(state *HierarchyState) []RootVersionReport {
+	var reports []RootVersionReport
+
+	for _, module := range rootModules(state) {
+		reports = append(reports, analyzeRootVersions(module))
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Path < reports[j].Path })
+	return reports
+}
+
+func analyzeRootVersions(root *Module) RootVersionReport {
+	report := RootVersionReport{Root: root.Name, Path: root.Path, MissingConstraints: []string{}}
+
+	constraints := make(map[string][]VersionConstraint)
+	var names []string
+	add := func(name string, module string, constraint string) {
+		if _, found := constraints[name]; !found {
+			names = append(names, name)
+		}
+		constraints[name] = append(constraints[name], VersionConstraint{Module: module, Constraint: constraint})
+	}
+
+	for _, module := range reachableModules(root) {
+		if !module.IsLoaded {
+			// remote modules are not loaded, nothing is known about their constraints
+			continue
+		}
+
+		settings := module.Terraform
+		if nil == settings {
+			settings = &ModuleSettings{}
+		}
+		if "" == settings.RequiredVersion {
+			report.MissingConstraints = append(report.MissingConstraints, module.Name+": required_version")
+		} else {
+			add("terraform", module.Name, settings.RequiredVersion)
+		}
+
+		for _, provider := range sortedUnique(requiredProviderNames(settings)) {
+			if version := settings.RequiredProviders[provider].Version; "" != version {
+				add(provider, module.Name, version)
+			}
+		}
+		// version argument of provider blocks, terraform 0.12 and older
+		for _, provider := range module.Providers {
+			if version, found := provider.Arguments["version"]; found {
+				add(provider.Name, module.Name, version)
+			}
+		}
+
+		for _, provider := range moduleProviderNames(module) {
+			if "" == settings.RequiredProviders[provider].Version && !hasProviderVersionArgument(module, provider) {
+				report.MissingConstraints = append(report.MissingConstraints, module.Name+": "+provider)
+			}
+		}
+	}
+
+	for _, name := range names {
+		requirement := VersionRequirement{Name: name, Constraints: constraints[name]}
+		var versions versionRange
+		for _, constraint := range requirement.Constraints {
+			if err := versions.Add(constraint.Constraint); nil != err {
+				requirement.Errors = append(requirement.Errors, fmt.Sprintf("%s: %v", constraint.Module, err))
+			}
+		}
+		requirement.Effective = versions.String()
+		requirement.Satisfiable = versions.IsSatisfiable()
+		report.Requirements = append(report.Requirements, requirement)
+	}
+	return report
+}
+
+// root first, every module once
+func reachableModules(root *Module) []*Module {
+	result := []*Module{root}
+	visited := map[*Module]bool{root: true}
+	for i := 0; i < len(result); i++ {
+		for _, instance := range result[i].ModuleInstances {
+			if nil != instance.Instance && !visited[instance.Instance] {
+				visited[instance.Instance] = true
+				result = append(result, instance.Instance)
+			}
+		}
+	}
+	return result
+}
+
+// providers used by resources or configured in the module
+func moduleProviderNames(module *Module) []string {
+	var result []string
+	for _, resource := range module.Resources {
+		name := defaultProviderName(resource.Type)
+		if "" != resource.Provider {
+			name = strings.SplitN(resource.Provider, ".", 2)[0]
+		}
+		if !Include(result, name) {
+			result = append(result, name)
+		}
+	}
+	for _, provider := range module.Providers {
+		if !Include(result, provider.Name) {
+			result = append(result, provider.Name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func hasProviderVersionArgument(module *Module, name string) bool {
+	for _, provider := range module.Providers {
+		if _, found := provider.Arguments["version"]; found && provider.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func requiredProviderNames(settings *ModuleSettings) []string {
+	var result []string
+	for name := range settings.RequiredProviders {
+		result = append(result, name)
+	}
+	return result
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestVersionRange(t *testing.T) {
+	Convey("Constraints must be intersected", t, func() {
+		var versions versionRange
+		So(versions.Add(">= 4.1, < 6"), ShouldBeNil)
+		So(versions.Add("~> 5.0"), ShouldBeNil)
+		So(versions.String(), ShouldEqual, ">= 5.0.0, < 6.0.0")
+		So(versions.IsSatisfiable(), ShouldBeTrue)
+
+		So(versions.Add("~> 4.0"), ShouldBeNil)
+		So(versions.IsSatisfiable(), ShouldBeFalse)
+
+		var pinned versionRange
+		So(pinned.Add("~> 1.2.3"), ShouldBeNil)
+		So(pinned.String(), ShouldEqual, ">= 1.2.3, < 1.3.0")
+		So(pinned.Add("1.2.5, != 1.2.5"), ShouldBeNil)
+		So(pinned.IsSatisfiable(), ShouldBeFalse)
+
+		So(pinned.Add(">= one"), ShouldNotBeNil)
+	})
+}
+
+func TestAnalyzeVersionConstraints(t *testing.T) {
+	Convey("Constraints of reachable modules must be combined per root", t, func() {
+		state := loadTestState("testdata/versions")
+		So(state.allModulesMap["."].Terraform.Backend, ShouldEqual, "s3")
+		So(state.allModulesMap["."].Terraform.BackendConfig["key"], ShouldEqual, "live/terraform.tfstate")
+
+		reports := AnalyzeVersionConstraints(state)
+		So(len(reports), ShouldEqual, 1)
+		report := reports[0]
+		So(report.IsSatisfiable(), ShouldBeFalse)
+
+		So(report.Requirements[0].Name, ShouldEqual, "terraform")
+		So(report.Requirements[0].Effective, ShouldEqual, ">= 0.11.14, < 0.12.0")
+		So(report.Requirements[0].Satisfiable, ShouldBeTrue)
+
+		So(report.Requirements[1].Name, ShouldEqual, "aws")
+		So(len(report.Requirements[1].Constraints), ShouldEqual, 3)
+		So(report.Requirements[1].Satisfiable, ShouldBeFalse)
+
+		So(report.MissingConstraints, ShouldResemble, []string{"modules.legacy: required_version", "modules.legacy: random"})
+	})
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/hashicorp/hcl/hcl/ast"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// polling watcher, keeps parsed files so that only changed files are parsed again and only their modules are rebuilt
+type WatchEvent struct {
+	Seq    int       `form:"Seq" json:"Seq" xml:"Seq"`
+	Time   time.Time `form:"Time" json:"Time" xml:"Time"`
+	Kind   string    `form:"Kind" json:"Kind" xml:"Kind"`
+	Path   string    `form:"Path" json:"Path" xml:"Path"`
+	Module string    `form:"Module" json:"Module" xml:"Module"`
+}
+
+type watchedFile struct {
+	ModTime time.Time
+	Size    int64
+	File    *ast.File
+	Err     error
+}
+
+type moduleWatcher struct {
+	terraformRoot string
+	awsResources  []Resource
+
+	// module root (relative to terraformRoot) -> files
+	modules map[string][]string
+	files   map[string]watchedFile
+	// terragrunt file -> module roots of the units including it, included files may be outside of module directories
+	includes map[string][]string
+	included map[string]watchedFile
+}
+
+// result of a scan, applied to the state separately so that parsing does not block readers
+type watchChanges struct {
+	modules  map[string][]string
+	files    map[string]watchedFile
+	included map[string]watchedFile
+	reload   []string
+	removed  []string
+	events   []WatchEvent
+}
+
+func (c *watchChanges) IsEmpty() bool {
+	return 0 == len(c.events)
+}
+
+func newModuleWatcher(terraformRoot string, awsResources []Resource) *moduleWatcher {
+	return &moduleWatcher{terraformRoot: terraformRoot, awsResources: awsResources}
+}
+
+// full load, every file is parsed
+func (w *moduleWatcher) Load() (*HierarchyState, error) {
+	w.modules = make(map[string][]string)
+	w.files = make(map[string]watchedFile)
+	w.includes = make(map[string][]string)
+	w.included = make(map[string]watchedFile)
+
+	changes, err := w.Scan()
+	if nil != err {
+		return nil, err
+	}
+
+	state := NewHierarchyState()
+	w.Apply(state, changes)
+	return state, nil
+}
+
+func (w *moduleWatcher) Scan() (*watchChanges, error) {
+	changes := &watchChanges{modules: make(map[string][]string), files: make(map[string]watchedFile), included: make(map[string]watchedFile)}
+
+	moduleDirs, err := discoverModules(w.terraformRoot, ".")
+	if nil != err {
+		return nil, err
+	}
+
+	reload := make(map[string]bool)
+	var parse []string
+	for _, moduleDir := range moduleDirs {
+		moduleRoot := moduleDir.Root
+		changes.modules[moduleRoot] = []string{}
+		if _, found := w.modules[moduleRoot]; !found {
+			changes.events = append(changes.events, WatchEvent{Kind: "module-added", Path: moduleRoot})
+			reload[moduleRoot] = true
+		}
+
+		files := moduleDir.Files
+		if nil != moduleDir.Terragrunt {
+			files = append(files[:len(files):len(files)], *moduleDir.Terragrunt)
+		}
+		for _, file := range files {
+			path := file.Path
+			changes.modules[moduleRoot] = append(changes.modules[moduleRoot], path)
+
+			previous, found := w.files[path]
+			current := watchedFile{ModTime: file.Info.ModTime(), Size: file.Info.Size()}
+			switch {
+			case !found:
+				changes.events = append(changes.events, WatchEvent{Kind: "file-added", Path: path})
+			case previous.ModTime != current.ModTime || previous.Size != current.Size:
+				changes.events = append(changes.events, WatchEvent{Kind: "file-changed", Path: path})
+			default:
+				changes.files[path] = previous
+				continue
+			}
+
+			changes.files[path] = current
+			if isModuleFileName(path) {
+				// terragrunt.hcl is read on apply
+				parse = append(parse, path)
+			}
+			reload[moduleRoot] = true
+		}
+	}
+
+	// units are rebuilt when a terragrunt file they include changes
+	for path, moduleRoots := range w.includes {
+		info, err := os.Stat(path)
+		var current watchedFile
+		if nil == err {
+			current = watchedFile{ModTime: info.ModTime(), Size: info.Size()}
+			changes.included[path] = current
+		}
+		previous, found := w.included[path]
+		if (nil == err && found && previous.ModTime == current.ModTime && previous.Size == current.Size) || (nil != err && !found) {
+			continue
+		}
+
+		if _, watched := w.files[path]; !watched {
+			kind := "file-changed"
+			if nil != err {
+				kind = "file-removed"
+			}
+			changes.events = append(changes.events, WatchEvent{Kind: kind, Path: path})
+		}
+		for _, moduleRoot := range moduleRoots {
+			if _, found := changes.modules[moduleRoot]; found {
+				reload[moduleRoot] = true
+			}
+		}
+	}
+
+	for path, parsed := range parseModuleFiles(parse, *jobs) {
+		file := changes.files[path]
+		file.File = parsed.File
+		file.Err = parsed.Err
+		changes.files[path] = file
+	}
+
+	for moduleRoot, files := range w.modules {
+		newFiles, found := changes.modules[moduleRoot]
+		if !found {
+			changes.events = append(changes.events, WatchEvent{Kind: "module-removed", Path: moduleRoot})
+			changes.removed = append(changes.removed, moduleRoot)
+			continue
+		}
+		for _, path := range files {
+			if !Include(newFiles, path) {
+				changes.events = append(changes.events, WatchEvent{Kind: "file-removed", Path: path})
+				reload[moduleRoot] = true
+			}
+		}
+	}
+
+	for moduleRoot := range reload {
+		changes.reload = append(changes.reload, moduleRoot)
+	}
+	sort.Strings(changes.reload)
+	sort.Strings(changes.removed)
+
+	for i := range changes.events {
+		changes.events[i].Module = getModuleName(w.terraformRoot, w.moduleRootOf(changes.events[i]))
+	}
+	return changes, nil
+}
+
+func (w *moduleWatcher) moduleRootOf(event WatchEvent) string {
+	if "module-added" == event.Kind || "module-removed" == event.Kind {
+		return event.Path
+	}
+	moduleRoot, err := filepath.Rel(w.terraformRoot, filepath.Dir(event.Path))
+	if nil != err {
+		return "."
+	}
+	return moduleRoot
+}
+
+// rebuilds changed modules from parsed files, state must not be read concurrently
+func (w *moduleWatcher) Apply(state *HierarchyState, changes *watchChanges) {
+	for _, moduleRoot := range changes.removed {
+		if module, found := state.allModulesMap[getModuleName(w.terraformRoot, moduleRoot)]; found {
+			state.ResetModule(module)
+		}
+	}
+
+	for _, moduleRoot := range changes.reload {
+		module := state.NewModule(getModuleName(w.terraformRoot, moduleRoot))
+		state.ResetModule(module)
+
+		var files []moduleFileAST
+		terragruntFile := ""
+		for _, path := range changes.modules[moduleRoot] {
+			if terragruntFileName == filepath.Base(path) {
+				terragruntFile = path
+			}
+			if file := changes.files[path]; nil != file.File || nil != file.Err {
+				files = append(files, moduleFileAST{Path: path, File: file.File, Err: file.Err})
+			}
+		}
+
+		buildModule(module, w.terraformRoot, moduleRoot, files, terragruntFile, w.awsResources, state)
+	}
+
+	w.modules = changes.modules
+	w.files = changes.files
+	w.included = changes.included
+	w.trackIncludes(state)
+}
+
+func (w *moduleWatcher) trackIncludes(state *HierarchyState) {
+	w.includes = make(map[string][]string)
+	for _, module := range state.AllModules {
+		if !module.IsLoaded || nil == module.Terragrunt {
+			continue
+		}
+		for _, path := range module.Terragrunt.Includes {
+			w.includes[path] = append(w.includes[path], module.Path)
+			if _, found := w.included[path]; found {
+				continue
+			}
+			if info, err := os.Stat(path); nil == err {
+				w.included[path] = watchedFile{ModTime: info.ModTime(), Size: info.Size()}
+			}
+		}
+	}
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// event log shared with http clients
+type watchEventLog struct {
+	mutex  sync.Mutex
+	seq    int
+	events []WatchEvent
+}
+
+const maxWatchEvents = 1000
+
+func (l *watchEventLog) Add(events []WatchEvent) []WatchEvent {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	for i := range events {
+		l.seq++
+		events[i].Seq = l.seq
+		events[i].Time = now
+	}
+	l.events = append(l.events, events...)
+	if len(l.events) > maxWatchEvents {
+		l.events = l.events[len(l.events)-maxWatchEvents:]
+	}
+	return events
+}
+
+func (l *watchEventLog) Since(seq int) []WatchEvent {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	result := make([]WatchEvent, 0)
+	for _, event := range l.events {
+		if event.Seq > seq {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// command
+func runWatch(args []string) error {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	interval := flags.Duration("interval", time.Second, "how often files are checked for changes")
+	flags.Parse(args)
+
+	awsResources, err := loadResources(*descriptionPath)
+	if err != nil {
+		return fmt.Errorf("error loading aws resources: %v", err)
+	}
+
+	watcher := newModuleWatcher(*rootDir, awsResources)
+	state, err := watcher.Load()
+	if nil != err {
+		return err
+	}
+	log.Infof("watching %s, %d modules loaded", *rootDir, len(state.AllModules))
+
+	var events watchEventLog
+	encoder := json.NewEncoder(os.Stdout)
+	for range time.Tick(*interval) {
+		changes, err := watcher.Scan()
+		if nil != err {
+			log.Error("watch: ", err)
+			continue
+		}
+		if changes.IsEmpty() {
+			continue
+		}
+		watcher.Apply(state, changes)
+		for _, event := range events.Add(changes.events) {
+			encoder.Encode(event)
+		}
+	}
+	return nil
+}
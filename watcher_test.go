@@ -0,0 +1,101 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestModuleWatcher(t *testing.T) {
+	Convey("Watcher must rebuild only changed modules", t, func() {
+		dir, err := ioutil.TempDir("", "hierarchy-watch")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		*rootDir = dir
+		So(os.MkdirAll(filepath.Join(dir, "app"), 0755), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, "main.tf"), []byte("variable \"a\" {}\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, "app", "main.tf"), []byte("variable \"b\" {}\n"), 0644), ShouldBeNil)
+
+		watcher := newModuleWatcher(dir, nil)
+		state, err := watcher.Load()
+		So(err, ShouldBeNil)
+		So(len(state.allModulesMap["."].Inputs), ShouldEqual, 1)
+
+		changes, err := watcher.Scan()
+		So(err, ShouldBeNil)
+		So(changes.IsEmpty(), ShouldBeTrue)
+
+		appInput := state.allModulesMap["app"].Inputs[0]
+		So(ioutil.WriteFile(filepath.Join(dir, "main.tf"), []byte("variable \"a\" {}\nvariable \"c\" {}\n"), 0644), ShouldBeNil)
+
+		changes, err = watcher.Scan()
+		So(err, ShouldBeNil)
+		So(changes.reload, ShouldResemble, []string{"."})
+		So(len(changes.events), ShouldEqual, 1)
+		So(changes.events[0].Kind, ShouldEqual, "file-changed")
+		So(changes.events[0].Module, ShouldEqual, ".")
+
+		watcher.Apply(state, changes)
+		So(len(state.allModulesMap["."].Inputs), ShouldEqual, 2)
+		So(state.allModulesMap["app"].Inputs[0], ShouldEqual, appInput)
+
+		So(os.RemoveAll(filepath.Join(dir, "app")), ShouldBeNil)
+		changes, err = watcher.Scan()
+		So(err, ShouldBeNil)
+		So(changes.removed, ShouldResemble, []string{"app"})
+		watcher.Apply(state, changes)
+		So(state.allModulesMap["app"].IsLoaded, ShouldBeFalse)
+	})
+	Convey("Watcher must rebuild units including a changed terragrunt file", t, func() {
+		dir, err := ioutil.TempDir("", "hierarchy-watch")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		*rootDir = dir
+		So(os.MkdirAll(filepath.Join(dir, "live", "app"), 0755), ShouldBeNil)
+		So(os.MkdirAll(filepath.Join(dir, "modules", "app"), 0755), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, "common.hcl"), []byte("inputs = {\n  region = \"us-east-1\"\n}\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, "live", "app", "terragrunt.hcl"), []byte(`include {
+  path = find_in_parent_folders("common.hcl")
+}
+
+terraform {
+  source = "../../modules//app"
+}
+`), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, "modules", "app", "main.tf"), []byte("variable \"region\" {}\n"), 0644), ShouldBeNil)
+
+		regionOf := func(state *HierarchyState) string {
+			for _, input := range state.allModulesMap["live.app"].Terragrunt.Inputs {
+				if "region" == input.Name {
+					return input.Value
+				}
+			}
+			return ""
+		}
+
+		watcher := newModuleWatcher(dir, nil)
+		state, err := watcher.Load()
+		So(err, ShouldBeNil)
+		So(regionOf(state), ShouldEqual, `"us-east-1"`)
+
+		So(ioutil.WriteFile(filepath.Join(dir, "common.hcl"), []byte("inputs = {\n  region = \"eu-central-1\"\n}\n"), 0644), ShouldBeNil)
+		changes, err := watcher.Scan()
+		So(err, ShouldBeNil)
+		So(changes.reload, ShouldResemble, []string{filepath.Join("live", "app")})
+		So(len(changes.events), ShouldEqual, 1)
+		So(changes.events[0].Kind, ShouldEqual, "file-changed")
+		So(changes.events[0].Path, ShouldEqual, filepath.Join(dir, "common.hcl"))
+
+		watcher.Apply(state, changes)
+		So(regionOf(state), ShouldEqual, `"eu-central-1"`)
+
+		changes, err = watcher.Scan()
+		So(err, ShouldBeNil)
+		So(changes.IsEmpty(), ShouldBeTrue)
+	})
+}
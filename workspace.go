@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+)
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// every root of a repository in one document, modules shared by roots are written once
+type WorkspaceRoot struct {
+	Name    string `form:"Name" json:"Name" xml:"Name"`
+	Path    string `form:"Path" json:"Path" xml:"Path"`
+	Backend string `form:"Backend" json:"Backend" xml:"Backend"`
+	// module address (module.a.module.b) -> module name in Modules
+	Instances map[string]string `form:"Instances" json:"Instances" xml:"Instances"`
+}
+
+type WorkspaceDocument struct {
+	// keyed by root path
+	Roots   map[string]WorkspaceRoot `form:"Roots" json:"Roots" xml:"Roots"`
+	Modules map[string]*Module       `form:"Modules" json:"Modules" xml:"Modules"`
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// command
+func runRoots(args []string) error {
+	flags := flag.NewFlagSet("roots", flag.ExitOnError)
+	list := flags.Bool("list", false, "print only root paths, one per line")
+	flags.Parse(args)
+
+	state, _, err := loadState()
+	if nil != err {
+		return err
+	}
+
+	if *list {
+		var buffer bytes.Buffer
+		for _, root := range workspaceRoots(state) {
+			buffer.WriteString(root.Path + "\n")
+		}
+		return writeOutput(buffer.Bytes())
+	}
+
+	jsonDocument, err := json.Marshal(NewWorkspaceDocument(state))
+	if nil != err {
+		return err
+	}
+	return writeOutput(jsonDocument)
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// discovery
+func NewWorkspaceDocument(state *HierarchyState) *WorkspaceDocument {
+	document := &WorkspaceDocument{Roots: make(map[string]WorkspaceRoot), Modules: make(map[string]*Module)}
+
+	for _, root := range workspaceRoots(state) {
+		workspaceRoot := WorkspaceRoot{Name: root.Name, Path: root.Path, Backend: rootBackend(root).Backend, Instances: make(map[string]string)}
+		collectWorkspaceInstances(root, "", []*Module{root}, workspaceRoot.Instances)
+		document.Roots[root.Path] = workspaceRoot
+
+		for _, module := range reachableModules(root) {
+			document.Modules[module.Name] = module
+		}
+	}
+	return document
+}
+
+// directories with a backend, a provider configuration or a terragrunt unit that no other module calls
+func workspaceRoots(state *HierarchyState) []*Module {
+	var result []*Module
+	for _, module := range rootModules(state) {
+		hasBackend := nil != module.Terraform && "" != module.Terraform.Backend
+		isUnit := nil != module.Terragrunt
+		if hasBackend || isUnit || 0 != len(module.Providers) {
+			result = append(result, module)
+		}
+	}
+	return result
+}
+
+func collectWorkspaceInstances(module *Module, address string, chain []*Module, instances map[string]string) {
+	for _, instance := range module.ModuleInstances {
+		instanceAddress := address + "module." + instance.InstanceName
+		instances[instanceAddress] = instance.ModulePath
+		if nil == instance.Instance || isModuleInList(chain, instance.Instance) {
+			continue
+		}
+		collectWorkspaceInstances(instance.Instance, instanceAddress+".", append(chain[:len(chain):len(chain)], instance.Instance), instances)
+	}
+}
+
+func isModuleInList(modules []*Module, module *Module) bool {
+	for _, m := range modules {
+		if m == module {
+			return true
+		}
+	}
+	return false
+}
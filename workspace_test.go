@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWorkspaceDocument(t *testing.T) {
+	Convey("Every root must be found and shared modules written once", t, func() {
+		state := loadTestState("testdata/workspace")
+
+		document := NewWorkspaceDocument(state)
+		So(len(document.Roots), ShouldEqual, 2)
+		So(document.Roots["live/prod"].Backend, ShouldEqual, "s3")
+		So(document.Roots["live/staging"].Backend, ShouldEqual, "local")
+		So(document.Roots["live/prod"].Instances, ShouldResemble, map[string]string{"module.app": "modules.app", "module.app.module.db": "modules.db"})
+
+		var names []string
+		for name := range document.Modules {
+			names = append(names, name)
+		}
+		So(sortedUnique(names), ShouldResemble, []string{"live.prod", "live.staging", "modules.app", "modules.db"})
+	})
+}